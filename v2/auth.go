@@ -0,0 +1,201 @@
+package restit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator authenticates a request before it is handled
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// Refresher is implemented by Authenticator which credentials
+// may expire. Case.Do calls Refresh and retry the request once
+// if the response status is 401 Unauthorized.
+type Refresher interface {
+	Refresh() error
+}
+
+// AuthenticatorFunc implements Authenticator
+type AuthenticatorFunc func(req *http.Request) error
+
+// Authenticate implements Authenticator
+func (fn AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return fn(req)
+}
+
+// BasicAuth returns an Authenticator for HTTP Basic authentication
+func BasicAuth(username, password string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// BearerAuth returns an Authenticator which sends the static
+// token as Bearer token
+func BearerAuth(token string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// OAuth2ClientCredentials implements Authenticator and Refresher
+// with OAuth2 client credentials grant. The token is fetched from
+// TokenURL on first use and cached until it expires.
+type OAuth2ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// Handler handles the token request. Defaults to HTTPHandler.
+	// Use HTTPTestHandler to fetch token from a local http.Handler.
+	Handler CaseHandler
+
+	mutex   sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Authenticate implements Authenticator
+func (o *OAuth2ClientCredentials) Authenticate(req *http.Request) (err error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.token == "" || (!o.expires.IsZero() && time.Now().After(o.expires)) {
+		if err = o.fetch(); err != nil {
+			return
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+o.token)
+	return
+}
+
+// Refresh implements Refresher
+func (o *OAuth2ClientCredentials) Refresh() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.fetch()
+}
+
+// fetch retrieves a new token from the token endpoint
+func (o *OAuth2ClientCredentials) fetch() (err error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	req, err := NewRequestWith("POST", o.TokenURL, form, FormEncoder)
+	if err != nil {
+		return
+	}
+	req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+	req.Header.Set("Accept", "application/json")
+
+	handler := o.Handler
+	if handler == nil {
+		handler = CaseHandlerFunc(HTTPHandler)
+	}
+	resp, err := handler.Handle(req)
+	if err != nil {
+		ctxErr := NewContextError("error requesting token (%s)", err)
+		ctxErr.Append("token_url", o.TokenURL)
+		return ctxErr
+	}
+	resp = CacheResponse(resp)
+	if resp.StatusCode() != http.StatusOK {
+		ctxErr := NewContextError("token endpoint responded with status %d", resp.StatusCode())
+		ctxErr.Append("token_url", o.TokenURL)
+		ctxErr.Append("response", resp.String())
+		return ctxErr
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body()).Decode(&result); err != nil || result.AccessToken == "" {
+		ctxErr := NewContextError("invalid token response")
+		ctxErr.Append("token_url", o.TokenURL)
+		ctxErr.Append("response", resp.String())
+		return ctxErr
+	}
+
+	o.token = result.AccessToken
+	o.expires = time.Time{}
+	if result.ExpiresIn > 0 {
+		o.expires = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return
+}
+
+// HMACAuth implements Authenticator by signing the request
+// method, path, body and date with a shared secret.
+//
+// The string to sign is:
+//
+//	METHOD + "\n" + REQUEST_URI + "\n" + DATE + "\n" + HEX(SHA256(BODY))
+//
+// The signature is sent in the Authorization header as:
+//
+//	HMAC keyId="KeyID",signature="BASE64(HMAC(secret, string to sign))"
+type HMACAuth struct {
+	KeyID  string
+	Secret []byte
+
+	// Hash is the hash function of HMAC. Defaults to sha256.New.
+	Hash func() hash.Hash
+}
+
+// Authenticate implements Authenticator
+func (h HMACAuth) Authenticate(req *http.Request) (err error) {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	signature, err := h.Sign(req)
+	if err != nil {
+		ctxErr := NewContextError("error signing request (%s)", err)
+		ctxErr.Append("key_id", h.KeyID)
+		return ctxErr
+	}
+	req.Header.Set("Authorization",
+		fmt.Sprintf(`HMAC keyId="%s",signature="%s"`, h.KeyID, signature))
+	return
+}
+
+// Sign returns the base64 encoded signature of the request.
+// The request body is restored after reading.
+func (h HMACAuth) Sign(req *http.Request) (signature string, err error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	bodyHash := sha256.Sum256(body)
+
+	hashFn := h.Hash
+	if hashFn == nil {
+		hashFn = sha256.New
+	}
+	mac := hmac.New(hashFn, h.Secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", req.Method, req.URL.RequestURI(),
+		req.Header.Get("Date"), hex.EncodeToString(bodyHash[:]))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
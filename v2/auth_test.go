@@ -0,0 +1,193 @@
+package restit_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+// authEchoHandler responds with the Authorization header received
+func authEchoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	})
+}
+
+func TestBasicAuth(t *testing.T) {
+	service := restit.NewHTTPTestService("/api", authEchoHandler())
+	service.Auth = restit.BasicAuth("foo", "bar")
+
+	resp, err := service.Retrieve("/post/1").Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "Basic Zm9vOmJhcg==", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// case without authentication
+	resp, err = service.Retrieve("/post/1").WithAuth(nil).Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	service := restit.NewHTTPTestService("/api", authEchoHandler())
+	service.Auth = restit.BearerAuth("some_token")
+
+	resp, err := service.Retrieve("/post/1").Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "Bearer some_token", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+
+	// dummy token endpoint issues a new token on every request
+	issued := 0
+	tokenHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "client" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"unsupported_grant_type"}`))
+			return
+		}
+		issued++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", issued),
+			"token_type":   "bearer",
+			"expires_in":   3600,
+		})
+	})
+
+	// dummy API only accept the latest token
+	apiHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want, have := fmt.Sprintf("Bearer token-%d", issued), r.Header.Get("Authorization"); want != have {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	})
+
+	auth := &restit.OAuth2ClientCredentials{
+		TokenURL:     "/oauth/token",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Handler:      restit.CaseHandlerFunc(restit.HTTPTestHandler(tokenHandler)),
+	}
+	service := restit.NewHTTPTestService("/api", apiHandler)
+	service.Auth = auth
+
+	// token is fetched and cached
+	for i := 0; i < 2; i++ {
+		resp, err := service.Retrieve("/post/1").
+			Expect(restit.StatusCodeIs(http.StatusOK)).
+			Do()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if want, have := "Bearer token-1", resp.String(); want != have {
+			t.Errorf("expected %#v, got %#v", want, have)
+		}
+	}
+
+	// token revoked by server: refresh and retry once
+	issued++
+	resp, err := service.Create(map[string]string{"foo": "bar"}, "/posts").
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "Bearer token-3", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// invalid credentials
+	service.Auth = &restit.OAuth2ClientCredentials{
+		TokenURL:     "/oauth/token",
+		ClientID:     "client",
+		ClientSecret: "wrong",
+		Handler:      restit.CaseHandlerFunc(restit.HTTPTestHandler(tokenHandler)),
+	}
+	_, err = service.Retrieve("/post/1").Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `ref="authentication" message="token endpoint responded with status 401" token_url="/oauth/token" response="{\"error\":\"invalid_client\"}"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
+
+func TestHMACAuth(t *testing.T) {
+	auth := restit.HMACAuth{
+		KeyID:  "key-1",
+		Secret: []byte("secret"),
+	}
+
+	// dummy API verifies the signature with the same secret
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature, err := auth.Sign(r)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		expected := fmt.Sprintf(`HMAC keyId="key-1",signature="%s"`, signature)
+		if r.Header.Get("Date") == "" || r.Header.Get("Authorization") != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(payload["foo"]))
+	})
+
+	service := restit.NewHTTPTestService("/api", handler)
+	service.Auth = auth
+
+	resp, err := service.Create(map[string]string{"foo": "bar"}, "/posts").
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "bar", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// tampered date fails the verification
+	_, err = service.Create(map[string]string{"foo": "bar"}, "/posts").
+		BeforeRequest(func(req *http.Request) error {
+			req.Header.Set("Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+			return nil
+		}).
+		WithAuth(restit.AuthenticatorFunc(func(req *http.Request) error {
+			if err := auth.Authenticate(req); err != nil {
+				return err
+			}
+			req.Header.Set("Date", "Tue, 03 Jan 2006 15:04:05 GMT")
+			return nil
+		})).
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if !strings.Contains(err.Error(), "expected 200, got 401") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,206 @@
+package restit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bodyDiff is the structural difference between the expected
+// value and the response body
+type bodyDiff struct {
+	added   []string // paths only in the response
+	removed []string // paths only in the expected value
+	changed []string // paths with different values
+}
+
+// Len returns the number of differences
+func (d *bodyDiff) Len() int {
+	return len(d.added) + len(d.removed) + len(d.changed)
+}
+
+// ContextError returns the ContextError of the differences
+func (d *bodyDiff) ContextError(format string, v ...interface{}) ContextError {
+	ctxErr := NewContextError(format, v...)
+	ctxErr.Prepend("ref", "response.body")
+	if len(d.added) > 0 {
+		ctxErr.Append("added", strings.Join(d.added, ", "))
+	}
+	if len(d.removed) > 0 {
+		ctxErr.Append("removed", strings.Join(d.removed, ", "))
+	}
+	if len(d.changed) > 0 {
+		ctxErr.Append("changed", strings.Join(d.changed, "; "))
+	}
+	return ctxErr
+}
+
+// diffPath returns the path for display
+func diffPath(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return strings.TrimPrefix(path, ".")
+}
+
+// diffKey returns the path of the key in the object at path
+func diffKey(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%#v]", path, key)
+	}
+	return path + "." + key
+}
+
+// compactJSON returns the compact JSON encoding of the decoded value
+func compactJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// valueEqual tests if the decoded JSON scalars are equal.
+// Numbers are compared by value.
+func valueEqual(want, have interface{}) bool {
+	if nw, ok := want.(json.Number); ok {
+		nh, ok := have.(json.Number)
+		if !ok {
+			return false
+		}
+		fw, errW := strconv.ParseFloat(string(nw), 64)
+		fh, errH := strconv.ParseFloat(string(nh), 64)
+		if errW != nil || errH != nil {
+			return nw == nh
+		}
+		return fw == fh
+	}
+	return want == have
+}
+
+// diff appends the differences between the decoded JSON values
+// at path. If subset is true, object keys and array items only in
+// have are ignored.
+func (d *bodyDiff) diff(path string, want, have interface{}, subset bool) {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		h, ok := have.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(w))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if hv, ok := h[key]; !ok {
+				d.removed = append(d.removed, diffPath(diffKey(path, key)))
+			} else {
+				d.diff(diffKey(path, key), w[key], hv, subset)
+			}
+		}
+		if subset {
+			return
+		}
+		keys = keys[:0]
+		for key := range h {
+			if _, ok := w[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			d.added = append(d.added, diffPath(diffKey(path, key)))
+		}
+		return
+	case []interface{}:
+		h, ok := have.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(w) || i < len(h); i++ {
+			elem := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(h):
+				d.removed = append(d.removed, diffPath(elem))
+			case i >= len(w):
+				if !subset {
+					d.added = append(d.added, diffPath(elem))
+				}
+			default:
+				d.diff(elem, w[i], h[i], subset)
+			}
+		}
+		return
+	default:
+		if valueEqual(want, have) {
+			return
+		}
+	}
+	d.changed = append(d.changed, fmt.Sprintf("%s: %s => %s",
+		diffPath(path), compactJSON(want), compactJSON(have)))
+}
+
+// decodeJSON decodes the raw JSON into generic values with
+// numbers as json.Number
+func decodeJSON(raw []byte) (v interface{}, err error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	err = dec.Decode(&v)
+	return
+}
+
+// body returns an Expectation which compares the response body
+// to the JSON encoding of v
+func body(verb string, v interface{}, subset bool) Expectation {
+	raw, encErr := toJSON(v)
+	var want interface{}
+	if encErr == nil {
+		want, encErr = decodeJSON(raw)
+	}
+	return Describe(fmt.Sprintf("body %ss %s", verb, raw), func(ctx context.Context, resp Response) (err error) {
+		if encErr != nil {
+			ctxErr := NewContextError("unable to encode expected value (%s)", encErr)
+			ctxErr.Prepend("ref", "response.body")
+			return ctxErr
+		}
+		node, err := resp.JSON()
+		if err != nil {
+			return
+		}
+		have, err := decodeJSON(node.Raw())
+		if err != nil {
+			return
+		}
+		d := &bodyDiff{}
+		d.diff("", want, have, subset)
+		switch n := d.Len(); n {
+		case 0:
+			return nil
+		case 1:
+			return d.ContextError("body does not %s the expected value (1 difference)", verb)
+		default:
+			return d.ContextError("body does not %s the expected value (%d differences)", verb, n)
+		}
+	})
+}
+
+// BodyContains tests if the JSON response body contains every field
+// of the JSON encoding of v (e.g. a struct or a map). Struct fields
+// omitted by the encoding (e.g. zero values tagged omitempty) are not
+// expected. Fields of the body not in v and array items beyond those
+// in v are ignored. On mismatch, the ContextError lists the paths
+// removed from (i.e. missing in) the body and the paths with changed
+// value.
+func BodyContains(v interface{}) Expectation {
+	return body("contain", v, true)
+}
+
+// BodyEquals tests if the JSON response body equals the JSON encoding
+// of v. Object keys order and number formatting are ignored. On mismatch,
+// the ContextError lists the paths added, removed and changed.
+func BodyEquals(v interface{}) Expectation {
+	return body("equal", v, false)
+}
@@ -0,0 +1,150 @@
+package restit_test
+
+import (
+	"context"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+const dummyBodyJSON = `{
+	"id": "post-1",
+	"title": "hello",
+	"score": 10.0,
+	"author": {"name": "alice", "roles": ["admin", "editor"]},
+	"odd.key": 1
+}`
+
+type dummyBodyAuthor struct {
+	Name string `json:"name"`
+}
+
+type dummyBodyPost struct {
+	ID     string           `json:"id"`
+	Score  int              `json:"score"`
+	Author dummyBodyAuthor  `json:"author"`
+	Editor *dummyBodyAuthor `json:"editor,omitempty"`
+	Tags   []string         `json:"tags,omitempty"`
+}
+
+func TestBody_Desc(t *testing.T) {
+	if want, have := `body contains {"id":"post-1"}`, restit.BodyContains(map[string]string{"id": "post-1"}).Desc(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := `body equals [1,2]`, restit.BodyEquals([]int{1, 2}).Desc(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestBody_Pass(t *testing.T) {
+	ctx := context.Background()
+	resp := jsonResponse(dummyBodyJSON)
+
+	tests := []restit.Expectation{
+		restit.BodyContains(dummyBodyPost{ID: "post-1", Score: 10, Author: dummyBodyAuthor{Name: "alice"}}),
+		restit.BodyContains(&dummyBodyPost{ID: "post-1", Score: 10, Author: dummyBodyAuthor{Name: "alice"}}),
+		restit.BodyContains(map[string]interface{}{
+			"author": map[string]interface{}{"roles": []string{"admin", "editor"}},
+		}),
+		restit.BodyContains(map[string]interface{}{
+			"author": map[string]interface{}{"roles": []string{"admin"}},
+		}),
+		restit.BodyContains(map[string]interface{}{}),
+		restit.BodyEquals(map[string]interface{}{
+			"odd.key": 1,
+			"score":   10,
+			"title":   "hello",
+			"id":      "post-1",
+			"author":  map[string]interface{}{"name": "alice", "roles": []string{"admin", "editor"}},
+		}),
+	}
+	for _, test := range tests {
+		if err := test.Do(ctx, resp); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.Desc(), err)
+		}
+	}
+}
+
+func TestBody_Fail(t *testing.T) {
+	ctx := context.Background()
+	resp := jsonResponse(dummyBodyJSON)
+
+	tests := []struct {
+		exp restit.Expectation
+		log string
+	}{
+		{
+			exp: restit.BodyContains(dummyBodyPost{ID: "post-2", Score: 10, Author: dummyBodyAuthor{Name: "bob"}}),
+			log: `ref="response.body" message="body does not contain the expected value (2 differences)" changed="author.name: \"bob\" => \"alice\"; id: \"post-2\" => \"post-1\""`,
+		},
+		{
+			exp: restit.BodyContains(map[string]interface{}{
+				"author":  map[string]interface{}{"roles": []string{"admin"}, "email": "a@b.c"},
+				"deleted": nil,
+				"title":   map[string]interface{}{"en": "hello"},
+			}),
+			log: `ref="response.body" message="body does not contain the expected value (3 differences)" removed="author.email, deleted" changed="title: {\"en\":\"hello\"} => \"hello\""`,
+		},
+		{
+			exp: restit.BodyContains(map[string]interface{}{
+				"author": map[string]interface{}{"roles": []string{"admin", "editor", "owner"}},
+			}),
+			log: `ref="response.body" message="body does not contain the expected value (1 difference)" removed="author.roles[2]"`,
+		},
+		{
+			exp: restit.BodyContains(dummyBodyPost{ID: "post-1", Score: 10, Author: dummyBodyAuthor{Name: "alice"}, Tags: []string{"a"}}),
+			log: `ref="response.body" message="body does not contain the expected value (1 difference)" removed="tags"`,
+		},
+		{
+			exp: restit.BodyContains(dummyBodyPost{ID: "post-1"}),
+			log: `ref="response.body" message="body does not contain the expected value (2 differences)" changed="author.name: \"\" => \"alice\"; score: 0 => 10.0"`,
+		},
+		{
+			exp: restit.BodyEquals(map[string]interface{}{
+				"id":      "post-1",
+				"title":   "hello",
+				"score":   10.5,
+				"author":  map[string]interface{}{"name": "alice", "roles": []string{"admin", "editor", "owner"}},
+				"created": "2020-01-01",
+			}),
+			log: `ref="response.body" message="body does not equal the expected value (4 differences)" added="[\"odd.key\"]" removed="author.roles[2], created" changed="score: 10.5 => 10.0"`,
+		},
+		{
+			exp: restit.BodyEquals([]string{"hello"}),
+			log: `ref="response.body" message="body does not equal the expected value (1 difference)" changed="$: [\"hello\"] => {\"author\":{\"name\":\"alice\",\"roles\":[\"admin\",\"editor\"]},\"id\":\"post-1\",\"odd.key\":1,\"score\":10.0,\"title\":\"hello\"}"`,
+		},
+		{
+			exp: restit.BodyEquals(func() {}),
+			log: `ref="response.body" message="unable to encode expected value (json: unsupported type: func())"`,
+		},
+	}
+	for _, test := range tests {
+		err := test.exp.Do(ctx, resp)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", test.exp.Desc())
+		} else if want, have := test.log, err.(restit.ContextError).Log(); want != have {
+			t.Errorf("%s\nexpected: %s\ngot:      %s", test.exp.Desc(), want, have)
+		}
+	}
+}
+
+func TestBody_ZeroValues(t *testing.T) {
+	type post struct {
+		Published bool   `json:"published"`
+		Count     int    `json:"count"`
+		Note      string `json:"note,omitempty"`
+	}
+	resp := jsonResponse(`{"published":true,"count":5,"note":"hello"}`)
+
+	// zero values are expected unless omitted by the encoding
+	err := restit.BodyContains(post{}).Do(context.Background(), resp)
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `ref="response.body" message="body does not contain the expected value (2 differences)" changed="count: 0 => 5; published: false => true"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+	if err := restit.BodyContains(post{Published: true, Count: 5}).Do(context.Background(), resp); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
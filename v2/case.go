@@ -1,10 +1,16 @@
 package restit
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
-
-	"golang.org/x/net/context"
+	"net/url"
+	"path"
+	"time"
 )
 
 // CaseHandler runs a given request and return a response interface
@@ -20,12 +26,107 @@ func (fn CaseHandlerFunc) Handle(req *http.Request) (resp Response, err error) {
 	return fn(req)
 }
 
+// RequestHook modifies or examines a request before it is handled
+type RequestHook func(req *http.Request) error
+
+// ResponseHook examines a response before the expectations run
+type ResponseHook func(resp Response) error
+
+// requestKey is the context key of the request
+type requestKey struct{}
+
+// RequestFromContext returns the request sent by the case, from
+// the context passed to the expectations. Returns nil if not found.
+func RequestFromContext(ctx context.Context) *http.Request {
+	req, _ := ctx.Value(requestKey{}).(*http.Request)
+	return req
+}
+
+// Observer observes the request and the cached response of every
+// run of a case, before the response hooks and expectations
+type Observer interface {
+	Observe(req *http.Request, resp Response)
+}
+
+// ObserverFunc implements Observer
+type ObserverFunc func(req *http.Request, resp Response)
+
+// Observe implements Observer
+func (fn ObserverFunc) Observe(req *http.Request, resp Response) {
+	fn(req, resp)
+}
+
 // Case contain all information of a single test case
 type Case struct {
 	Request      *http.Request
 	Context      context.Context
 	Handler      CaseHandler
 	Expectations []Expectation
+
+	payload    interface{}
+	encoder    BodyEncoder
+	pathTmpl   string
+	pathParams map[string]string
+	err        error
+	encodeErr  error // error of encoding payload, reset by WithEncoder
+
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+	auth          Authenticator
+	observers     []Observer
+	timeout       time.Duration
+	polling       *polling
+	session       *Session
+	vars          *Vars
+	captures      []capture
+	soft          bool
+	showSecrets   bool
+}
+
+// setBody encodes the payload with the given encoder and
+// replace the request body with it
+func (c *Case) setBody(payload interface{}, enc BodyEncoder) (err error) {
+	if enc == nil {
+		enc = JSONEncoder
+	}
+	body, contentType, err := enc.Encode(payload)
+	if err != nil {
+		return
+	}
+	tmp, err := http.NewRequest(c.Request.Method, "/", body)
+	if err != nil {
+		return
+	}
+	c.Request.Body = tmp.Body
+	c.Request.GetBody = tmp.GetBody
+	c.Request.ContentLength = tmp.ContentLength
+	if body == nil || contentType == "" {
+		c.Request.Header.Del("Content-Type")
+	} else {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+	c.payload, c.encoder = payload, enc
+	return
+}
+
+// fail records the first error occurred while building the case.
+// The error will be returned by Do.
+func (c *Case) fail(err error) *Case {
+	if c.err == nil {
+		c.err = err
+	}
+	return c
+}
+
+// WithEncoder re-encodes the payload given to Service.NewCase
+// with the BodyEncoder and updates the request body and
+// Content-Type header.
+func (c *Case) WithEncoder(enc BodyEncoder) *Case {
+	if c.Request == nil {
+		return c.fail(fmt.Errorf("case.Request is nil"))
+	}
+	c.encodeErr = c.setBody(c.payload, enc)
+	return c
 }
 
 // AddHeader add given header key-value pair to request
@@ -34,6 +135,19 @@ func (c *Case) AddHeader(key, value string) *Case {
 	return c
 }
 
+// SetHeader set given header key-value pair to request,
+// replacing any existing value (e.g. service default)
+func (c *Case) SetHeader(key, value string) *Case {
+	c.Request.Header.Set(key, value)
+	return c
+}
+
+// DelHeader removes given header key from request
+func (c *Case) DelHeader(key string) *Case {
+	c.Request.Header.Del(key)
+	return c
+}
+
 // AddQuery add given query key-value pair to request
 func (c *Case) AddQuery(key, value string) *Case {
 	q := c.Request.URL.Query()
@@ -42,6 +156,136 @@ func (c *Case) AddQuery(key, value string) *Case {
 	return c
 }
 
+// SetQuery set given query key-value pair to request,
+// replacing any existing value (e.g. service default)
+func (c *Case) SetQuery(key, value string) *Case {
+	q := c.Request.URL.Query()
+	q.Set(key, value)
+	c.Request.URL.RawQuery = q.Encode()
+	return c
+}
+
+// DelQuery removes given query key from request
+func (c *Case) DelQuery(key string) *Case {
+	q := c.Request.URL.Query()
+	q.Del(key)
+	c.Request.URL.RawQuery = q.Encode()
+	return c
+}
+
+// BeforeRequest appends a hook to run on the request
+// before it is handled
+func (c *Case) BeforeRequest(hook RequestHook) *Case {
+	c.requestHooks = append(c.requestHooks, hook)
+	return c
+}
+
+// AfterResponse appends a hook to run on the response
+// before the expectations
+func (c *Case) AfterResponse(hook ResponseHook) *Case {
+	c.responseHooks = append(c.responseHooks, hook)
+	return c
+}
+
+// Observe adds an observer of the request and response
+func (c *Case) Observe(observer Observer) *Case {
+	c.observers = append(c.observers, observer)
+	return c
+}
+
+// multipartForm returns the multipart form of the case
+// or a new one if the case payload is not multipart yet
+func (c *Case) multipartForm() *MultipartForm {
+	if form, ok := c.payload.(*MultipartForm); ok && form != nil {
+		return form
+	}
+	return &MultipartForm{Fields: make(url.Values)}
+}
+
+// AddFormField adds a field to the multipart/form-data body
+// of the request. Any non-multipart payload will be replaced.
+func (c *Case) AddFormField(key, value string) *Case {
+	if c.Request == nil {
+		return c.fail(fmt.Errorf("case.Request is nil"))
+	}
+	form := c.multipartForm()
+	form.Fields.Add(key, value)
+	if err := c.setBody(form, MultipartEncoder); err != nil {
+		return c.fail(err)
+	}
+	return c
+}
+
+// AttachFile reads the reader and adds it as a file of the given
+// field name and file name to the multipart/form-data body of
+// the request. Any non-multipart payload will be replaced.
+func (c *Case) AttachFile(field, name string, reader io.Reader) *Case {
+	if c.Request == nil {
+		return c.fail(fmt.Errorf("case.Request is nil"))
+	}
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return c.fail(fmt.Errorf("error reading file %#v (%s)", name, err))
+	}
+	form := c.multipartForm()
+	form.Files = append(form.Files, MultipartFile{
+		Field:       field,
+		Name:        name,
+		ContentType: mime.TypeByExtension(path.Ext(name)),
+		Content:     content,
+	})
+	if err := c.setBody(form, MultipartEncoder); err != nil {
+		return c.fail(err)
+	}
+	return c
+}
+
+// WithPathParam fills the named placeholder (e.g. "{id}") in the
+// path given to Service.NewCase with the escaped value
+func (c *Case) WithPathParam(name string, value interface{}) *Case {
+	if c.Request == nil {
+		return c.fail(fmt.Errorf("case.Request is nil"))
+	}
+	if c.pathParams == nil {
+		c.pathParams = make(map[string]string)
+	}
+	c.pathParams[name] = fmt.Sprint(value)
+	rendered, _ := renderPath(c.pathTmpl, c.pathParams)
+	if err := setPath(c.Request.URL, rendered); err != nil {
+		return c.fail(err)
+	}
+	return c
+}
+
+// WithSession sets the Session of the case, replacing the
+// one from Service. Use nil to send request without session.
+func (c *Case) WithSession(session *Session) *Case {
+	c.session = session
+	return c
+}
+
+// SoftAssert sets if the case should run every expectation
+// even if some failed. All failures are returned as *MultiError.
+func (c *Case) SoftAssert(enabled bool) *Case {
+	c.soft = enabled
+	return c
+}
+
+// Timeout sets the maximum duration for the request to
+// complete, including reading the response body
+func (c *Case) Timeout(d time.Duration) *Case {
+	c.timeout = d
+	return c
+}
+
+// WithAuth sets the Authenticator of the case, replacing
+// the one from Service. Use nil to send request without
+// authentication.
+func (c *Case) WithAuth(auth Authenticator) *Case {
+	c.auth = auth
+	return c
+}
+
 // ModifyCase allows user do whatever to the case (even
 // rewrite a new one) without interrupting the chaining.
 func (c *Case) ModifyCase(fn func(c *Case) *Case) *Case {
@@ -66,26 +310,143 @@ func (c Case) Do() (resp Response, err error) {
 	if c.Handler == nil {
 		return nil, fmt.Errorf("case.Handler is nil")
 	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.encodeErr != nil {
+		return nil, c.encodeErr
+	}
+	if _, missing := renderPath(c.pathTmpl, c.pathParams); len(missing) > 0 {
+		ctxErr := NewContextError("path parameter %#v is not filled", missing[0])
+		ctxErr.Prepend("ref", "request.path")
+		ctxErr.Append("path", c.pathTmpl)
+		return nil, ctxErr
+	}
 
-	// do the request
-	resp, err = c.Handler.Handle(c.Request)
-	if err != nil {
+	// make request body replayable so the case can be run again
+	if err = c.bufferBody(); err != nil {
+		return
+	}
+
+	if c.polling != nil {
+		return c.poll()
+	}
+	return c.do()
+}
+
+// do runs the request and the expectations once
+func (c Case) do() (resp Response, err error) {
+
+	// attach context (with timeout, if any) to the request
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		c.Context, cancel = context.WithTimeout(c.Context, c.timeout)
+		defer cancel()
+
+		// read the body before the context is cancelled
+		defer func() {
+			if resp != nil {
+				ioutil.ReadAll(resp.Body())
+			}
+		}()
+	}
+	// work on a deep copy (including URL and header) so the
+	// case can be run again
+	c.Request = c.Request.Clone(c.Context)
+	c.rewind()
+
+	// interpolate variables into the request
+	if c.vars != nil {
+		if err = c.vars.interpolateRequest(c.Request, c.desc()); err != nil {
+			return
+		}
+	}
+
+	// add session cookies and CSRF token
+	if c.session != nil {
+		c.session.apply(c.Request)
+	}
+
+	// run all request hooks
+	for i, hook := range c.requestHooks {
+		if err = hook(c.Request); err != nil {
+			cErr := ExpandError(err)
+			cErr.Prepend("before_request", i)
+			return nil, cErr
+		}
+	}
+
+	// authenticate and do the request
+	if resp, err = c.handle(); err != nil {
 		return
 	}
 
+	// refresh credentials and retry once if unauthorized
+	if refresher, ok := c.auth.(Refresher); ok &&
+		resp.StatusCode() == http.StatusUnauthorized && c.rewind() {
+		if rawResp, ok := resp.Raw().(*http.Response); ok {
+			rawResp.Body.Close()
+		}
+		if err = refresher.Refresh(); err != nil {
+			cErr := ExpandError(err)
+			cErr.Prepend("ref", "authentication")
+			return nil, cErr
+		}
+		if resp, err = c.handle(); err != nil {
+			return
+		}
+	}
+
 	// wrap resulting Response with cachedResponse
 	resp = CacheResponse(resp)
+	defer releaseBody(resp)
+
+	// report the request and response to observers
+	for _, observer := range c.observers {
+		observer.Observe(c.Request, resp)
+	}
+
+	// store session cookies and CSRF token
+	if c.session != nil {
+		c.session.update(c.Request, resp)
+	}
+
+	// run all response hooks
+	for i, hook := range c.responseHooks {
+		if err = hook(resp); err != nil {
+			cErr := ExpandError(err)
+			cErr.Prepend("after_response", i)
+			err = cErr
+			return
+		}
+	}
 
 	// run all expectations
+	var errs []ContextError
+	ctx := context.WithValue(c.Context, requestKey{}, c.Request)
 	for i, expect := range c.Expectations {
-		if err = expect.Do(c.Context, resp); err != nil {
-			var cErr ContextError
-			var ok bool
-			if cErr, ok = err.(ContextError); !ok {
-				cErr = NewContextError(err.Error())
-			}
+		if err = expect.Do(ctx, resp); err != nil {
+			cErr := ExpandError(err)
 			cErr.Prepend("desc", expect.Desc())
 			cErr.Prepend("expectation", i)
+			cErr.Append("curl", curlCommand(c.Request, !c.showSecrets))
+			if !c.soft {
+				err = cErr
+				return
+			}
+			errs = append(errs, cErr)
+		}
+	}
+	if len(errs) > 0 {
+		err = &MultiError{Errors: errs}
+		return
+	}
+
+	// capture variables from the response
+	for _, capture := range c.captures {
+		if err = capture.Do(c.vars, resp); err != nil {
+			cErr := ExpandError(err)
+			cErr.Prepend("capture", capture.name)
 			err = cErr
 			return
 		}
@@ -94,6 +455,88 @@ func (c Case) Do() (resp Response, err error) {
 	return
 }
 
+// releaseBody buffers the body of the cached response and closes
+// the raw body, so the connection can be reused
+func releaseBody(resp Response) {
+	io.Copy(ioutil.Discard, resp.Body())
+	if rawResp, ok := resp.Raw().(*http.Response); ok {
+		rawResp.Body.Close()
+	}
+}
+
+// handle authenticates the request and handles it with
+// the CaseHandler. Errors of the handler have the curl
+// command of the request.
+func (c Case) handle() (resp Response, err error) {
+	if c.auth != nil {
+		if err = c.auth.Authenticate(c.Request); err != nil {
+			cErr := ExpandError(err)
+			cErr.Prepend("ref", "authentication")
+			return nil, cErr
+		}
+	}
+	if resp, err = c.Handler.Handle(c.Request); err != nil {
+		if c.Request.Context().Err() != nil {
+			err = contextDoneError(c.Request)
+		}
+		cErr := ExpandError(err)
+		cErr.Append("curl", curlCommand(c.Request, !c.showSecrets))
+		err = cErr
+	}
+	return
+}
+
+// contextDoneError returns the ContextError of a request which
+// context is cancelled or exceeded its deadline
+func contextDoneError(req *http.Request) ContextError {
+	ctx := req.Context()
+	msg := "request cancelled"
+	if ctx.Err() == context.DeadlineExceeded {
+		msg = "request timeout"
+	}
+	ctxErr := NewContextError("%s (%s)", msg, ctx.Err())
+	ctxErr.Prepend("ref", "request")
+	ctxErr.Append("method", req.Method)
+	ctxErr.Append("url", req.URL.String())
+	return ctxErr
+}
+
+// bufferBody reads the request body into memory, if it is not
+// replayable, so the request can be sent again
+func (c *Case) bufferBody() (err error) {
+	req := c.Request
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return
+	}
+	req.Body.Close()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return
+}
+
+// rewind resets the request body for the request to be
+// sent again. Returns false if the body cannot be replayed.
+func (c Case) rewind() bool {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return true
+	}
+	if c.Request.GetBody == nil {
+		return false
+	}
+	body, err := c.Request.GetBody()
+	if err != nil {
+		return false
+	}
+	c.Request.Body = body
+	return true
+}
+
 // Expectation stores procedure to run
 // the expection on the result
 type Expectation interface {
@@ -1,14 +1,17 @@
 package restit_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
-	"golang.org/x/net/context"
-
 	restit "github.com/go-restit/restit/v2"
 )
 
@@ -273,13 +276,18 @@ func TestCase_ExpectationErr(t *testing.T) {
 		},
 	}
 
+	curl, err := testCase.Curl()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
 	if _, err := testCase.Do(); err == nil {
 		t.Error("failed to trigger error")
 	} else if want, have := "dummy error", err.Error(); want != have {
 		t.Errorf("expected %#v, got %#v", want, have)
 	} else if ctxErr, ok := err.(restit.ContextError); !ok {
 		t.Errorf("expected restit.ContextError, got %#v", err)
-	} else if want, have := `expectation=1 desc="dummy test 2" message="dummy error"`, ctxErr.Log(); want != have {
+	} else if want, have := fmt.Sprintf(`expectation=1 desc="dummy test 2" message="dummy error" curl=%#v`,
+		curl), ctxErr.Log(); want != have {
 		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
 	}
 }
@@ -317,13 +325,18 @@ func TestCase_ExpectationContextErr(t *testing.T) {
 		},
 	}
 
+	curl, err := testCase.Curl()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
 	if _, err := testCase.Do(); err == nil {
 		t.Error("failed to trigger error")
 	} else if want, have := "dummy error", err.Error(); want != have {
 		t.Errorf("expected %#v, got %#v", want, have)
 	} else if ctxErr, ok := err.(restit.ContextError); !ok {
 		t.Errorf("expected restit.ContextError, got %#v", err)
-	} else if want, have := `expectation=1 desc="dummy test 2" message="dummy error" foo="bar"`, ctxErr.Log(); want != have {
+	} else if want, have := fmt.Sprintf(`expectation=1 desc="dummy test 2" message="dummy error" foo="bar" curl=%#v`,
+		curl), ctxErr.Log(); want != have {
 		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
 	}
 }
@@ -379,3 +392,184 @@ func TestCase_Expect(t *testing.T) {
 		t.Errorf("expected %#v, got %#v", want, have)
 	}
 }
+
+func TestCase_Multipart(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1024); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		file, header, err := r.FormFile("avatar")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		defer file.Close()
+		b, _ := ioutil.ReadAll(file)
+		fmt.Fprintf(w, "%s|%s|%s|%s", r.FormValue("title"),
+			header.Filename, header.Header.Get("Content-Type"), b)
+	})
+
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	services := map[string]*restit.Service{
+		"HTTPTestService": restit.NewHTTPTestService("/api", handler),
+		"HTTPService":     restit.NewHTTPService(testServer.URL + "/api"),
+	}
+
+	for name, service := range services {
+		resp, err := service.Create(nil, "avatar").
+			AddFormField("title", "my avatar").
+			AttachFile("avatar", "avatar.png", strings.NewReader("dummy image")).
+			Expect(restit.StatusCodeIs(http.StatusOK)).
+			Do()
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %s", name, err)
+			continue
+		}
+		if want, have := "my avatar|avatar.png|image/png|dummy image", resp.String(); want != have {
+			t.Errorf("[%s] expected %#v, got %#v", name, want, have)
+		}
+	}
+}
+
+func TestCase_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	services := map[string]*restit.Service{
+		"HTTPTestService": restit.NewHTTPTestService("/api", handler),
+		"HTTPService":     restit.NewHTTPService(testServer.URL + "/api"),
+	}
+
+	for name, service := range services {
+		_, err := service.Retrieve("/post/1").
+			Timeout(50 * time.Millisecond).
+			Do()
+		if err == nil {
+			t.Errorf("[%s] expected error, got nil", name)
+			continue
+		}
+		if want, have := "request timeout (context deadline exceeded)", err.Error(); want != have {
+			t.Errorf("[%s] expected %#v, got %#v", name, want, have)
+		}
+		if ctxErr, ok := err.(restit.ContextError); !ok {
+			t.Errorf("[%s] expected restit.ContextError, got %#v", name, err)
+		} else if want, have := "request", ctxErr.Get("ref"); want != have {
+			t.Errorf("[%s] expected %#v, got %#v", name, want, have)
+		} else if curl, _ := ctxErr.Get("curl").(string); !strings.HasSuffix(curl, "/api/post/1") {
+			t.Errorf("[%s] unexpected curl: %#v", name, ctxErr.Get("curl"))
+		}
+	}
+}
+
+func TestCase_TimeoutGoroutineLeak(t *testing.T) {
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // ignores the request context
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		if _, err := service.Retrieve("/post/1").
+			Timeout(time.Millisecond).
+			Do(); err == nil {
+			t.Fatalf("expected error, got nil")
+		}
+	}
+	close(release)
+
+	// handler goroutines should exit once the handler returns
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if want, have := before, runtime.NumGoroutine(); have > want {
+		t.Errorf("expected at most %d goroutines, got %d", want, have)
+	}
+}
+
+func TestCase_ContextCancel(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	testCase := service.Retrieve("/post/1")
+	testCase.Context = ctx
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := testCase.Do(); err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "request cancelled (context canceled)", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestCase_SoftAssert(t *testing.T) {
+	errDummy := fmt.Errorf("dummy error")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+	service.SoftAssert = true
+
+	_, err := service.Retrieve("/post/1").
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Expect(restit.Describe("passing test", func(ctx context.Context, resp restit.Response) error {
+			return nil
+		})).
+		Expect(restit.Describe("dummy test", func(ctx context.Context, resp restit.Response) error {
+			return errDummy
+		})).
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if want, have := "2 failures: expected 200, got 404; dummy error", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := `expectation=0 desc="status code is 200" ref="header status code" message="expected 200, got 404" curl="curl http://localhost/api/post/1"`+"\n"+
+		`expectation=2 desc="dummy test" message="dummy error" curl="curl http://localhost/api/post/1"`, err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+	if !errors.Is(err, errDummy) {
+		t.Errorf("expected errors.Is to find errDummy")
+	}
+	var mErr *restit.MultiError
+	if !errors.As(err, &mErr) {
+		t.Errorf("expected errors.As to find *restit.MultiError")
+	} else if want, have := 2, len(mErr.Errors); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// per case opt-out
+	_, err = service.Retrieve("/post/1").
+		SoftAssert(false).
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Expect(restit.Describe("dummy test", func(ctx context.Context, resp restit.Response) error {
+			return errDummy
+		})).
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := "expected 200, got 404", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
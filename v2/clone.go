@@ -0,0 +1,54 @@
+package restit
+
+import (
+	"net/url"
+)
+
+// Clone returns a deep copy of the case, including the request
+// (with a replayable body), headers, context, hooks and
+// expectations. The clone can be modified and run without
+// affecting the original.
+func (c *Case) Clone() *Case {
+	clone := *c
+	if c.Request != nil {
+		if err := c.bufferBody(); err != nil {
+			clone.fail(err)
+		}
+		clone.Request = c.Request.Clone(c.Request.Context())
+		if c.Request.GetBody != nil {
+			clone.Request.Body, _ = c.Request.GetBody()
+		}
+	}
+	clone.Expectations = append([]Expectation(nil), c.Expectations...)
+	clone.requestHooks = append([]RequestHook(nil), c.requestHooks...)
+	clone.responseHooks = append([]ResponseHook(nil), c.responseHooks...)
+	clone.observers = append([]Observer(nil), c.observers...)
+	clone.captures = append([]capture(nil), c.captures...)
+	if c.pathParams != nil {
+		clone.pathParams = make(map[string]string, len(c.pathParams))
+		for name, value := range c.pathParams {
+			clone.pathParams[name] = value
+		}
+	}
+	if form, ok := c.payload.(*MultipartForm); ok && form != nil {
+		formClone := &MultipartForm{
+			Fields: make(url.Values, len(form.Fields)),
+			Files:  append([]MultipartFile(nil), form.Files...),
+		}
+		for key, vals := range form.Fields {
+			formClone.Fields[key] = append([]string(nil), vals...)
+		}
+		clone.payload = formClone
+	}
+	if c.polling != nil {
+		polling := *c.polling
+		clone.polling = &polling
+	}
+	return &clone
+}
+
+// Variant clones the case as a template and modifies the clone
+// with the given function. The template case is unchanged.
+func (c *Case) Variant(fn func(c *Case) *Case) *Case {
+	return fn(c.Clone())
+}
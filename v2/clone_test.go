@@ -0,0 +1,83 @@
+package restit_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+// cloneEchoHandler responds with the request line, header and body received
+func cloneEchoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.RequestURI(), r.Header.Get("X-Foo"), b)
+	})
+}
+
+func TestCase_DoTwice(t *testing.T) {
+	service := restit.NewHTTPTestService("/api", cloneEchoHandler())
+	testCase := service.Create(map[string]string{"foo": "bar"}, "/posts")
+	for i := 0; i < 2; i++ {
+		resp, err := testCase.Do()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if want, have := `POST /api/posts  {"foo":"bar"}`, resp.String(); want != have {
+			t.Errorf("run %d: expected %#v, got %#v", i, want, have)
+		}
+	}
+}
+
+func TestCase_Clone(t *testing.T) {
+	service := restit.NewHTTPTestService("/api", cloneEchoHandler())
+	tmpl := service.Update(map[string]string{"foo": "bar"}, "/post/{id}").
+		AddHeader("X-Foo", "template").
+		Expect(restit.StatusCodeIs(http.StatusOK))
+
+	variant1 := tmpl.Clone().
+		WithPathParam("id", 1).
+		SetHeader("X-Foo", "variant1").
+		Expect(restit.Describe("dummy", func(ctx context.Context, resp restit.Response) error {
+			return nil
+		}))
+	variant2 := tmpl.Variant(func(c *restit.Case) *restit.Case {
+		return c.WithPathParam("id", 2).AddQuery("hello", "world")
+	})
+
+	if want, have := 1, len(tmpl.Expectations); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := 2, len(variant1.Expectations); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	tests := []struct {
+		c    *restit.Case
+		want string
+	}{
+		{variant1, `PUT /api/post/1 variant1 {"foo":"bar"}`},
+		{variant2, `PUT /api/post/2?hello=world template {"foo":"bar"}`},
+		{variant2.Clone(), `PUT /api/post/2?hello=world template {"foo":"bar"}`},
+	}
+	for i, test := range tests {
+		resp, err := test.c.Do()
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %s", i, err)
+			continue
+		}
+		if have := resp.String(); test.want != have {
+			t.Errorf("case %d: expected %#v, got %#v", i, test.want, have)
+		}
+	}
+
+	// template is still incomplete
+	if _, err := tmpl.Do(); err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := `path parameter "id" is not filled`, err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
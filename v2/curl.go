@@ -0,0 +1,364 @@
+package restit
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// shellQuote quotes the string for POSIX shell
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			strings.ContainsRune("-_./:=@,+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// curlCommand renders the request as curl command line. The body
+// is read with req.GetBody, if any. If redact is true, values of
+// the SensitiveHeaders are replaced by Redacted. Relative URLs are
+// rendered as absolute (see absoluteURL).
+func curlCommand(req *http.Request, redact bool) string {
+	args := []string{"curl"}
+	if req.Method != "" && req.Method != "GET" {
+		args = append(args, "-X", req.Method)
+	}
+	args = append(args, shellQuote(absoluteURL(req)))
+
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, val := range req.Header[key] {
+			if redact && isSensitiveHeader(key) {
+				val = Redacted
+			}
+			args = append(args, "-H", shellQuote(key+": "+val))
+		}
+	}
+
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil && body != http.NoBody {
+			data, _ := ioutil.ReadAll(body)
+			body.Close()
+			if len(data) > 0 {
+				args = append(args, "--data-raw", shellQuote(string(data)))
+			}
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// Curl renders the request of the case as a curl command.
+// Variables, session and authentication are not applied until
+// the case runs; the command of the request actually sent is
+// attached to the ContextError of failed expectations with
+// key "curl". Values of the SensitiveHeaders are redacted
+// unless ShowSecrets is enabled.
+//
+// Returns error if the request is nil or its body cannot
+// be read.
+func (c *Case) Curl() (string, error) {
+	if c.Request == nil {
+		return "", fmt.Errorf("case.Request is nil")
+	}
+	if err := c.bufferBody(); err != nil {
+		return "", err
+	}
+	return curlCommand(c.Request, !c.showSecrets), nil
+}
+
+// ShowSecrets sets if the curl commands of the case (see Curl)
+// should include the values of the SensitiveHeaders
+func (c *Case) ShowSecrets(enabled bool) *Case {
+	c.showSecrets = enabled
+	return c
+}
+
+// splitShellWords splits the command line into words with POSIX
+// shell quoting rules
+func splitShellWords(line string) (words []string, err error) {
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case ch == '\\' && i+1 < len(line):
+			i++
+			if line[i] != '\n' {
+				word.WriteByte(line[i])
+				inWord = true
+			}
+		case ch == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case ch == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("$`\"\\\n", line[i+1]) >= 0 {
+					i++
+					if line[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return
+}
+
+// curlShortArgs are the short curl options with argument
+const curlShortArgs = "XHdu"
+
+// expandShortFlags splits combined short flags (e.g. "-sS" or
+// "-XPOST") into separate words. Other words are returned as-is.
+func expandShortFlags(word string) []string {
+	if len(word) <= 2 || word[0] != '-' || word[1] == '-' {
+		return []string{word}
+	}
+	var words []string
+	for i := 1; i < len(word); i++ {
+		words = append(words, "-"+word[i:i+1])
+		if strings.IndexByte(curlShortArgs, word[i]) >= 0 {
+			if i+1 < len(word) {
+				words = append(words, word[i+1:])
+			}
+			break
+		}
+	}
+	return words
+}
+
+// readCurlData reads the data argument of the option. Data
+// starting with "@" is read from the file, except for --data-raw.
+func readCurlData(option, val string) (data string, err error) {
+	if option == "--data-raw" || !strings.HasPrefix(val, "@") {
+		return val, nil
+	}
+	name := val[1:]
+	if name == "-" {
+		return "", fmt.Errorf("reading data from stdin is not supported")
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	data = string(b)
+	switch option {
+	case "-d", "--data", "--data-ascii":
+		// curl strips newlines of the file
+		data = strings.NewReplacer("\r", "", "\n", "").Replace(data)
+	}
+	return
+}
+
+// curlFlagsIgnored are curl flags without argument which do not
+// affect the request
+var curlFlagsIgnored = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true,
+	"-v": true, "--verbose": true, "-i": true, "--include": true,
+	"-k": true, "--insecure": true, "-L": true, "--location": true,
+	"--compressed": true, "-f": true, "--fail": true,
+}
+
+// FromCurl parses the curl command line into a Case of the service.
+// Relative URLs are resolved against the service BaseURL. Supported
+// options are -X, -H, -d (and its --data-* variants), --json, -u,
+// -G, -I and --url. Short flags may be combined (e.g. "-sS" or
+// "-XPOST"), and data may be read from file with "@filename".
+// Parse errors are returned by Case.Do.
+func FromCurl(s *Service, command string) *Case {
+	var (
+		method, rawURL string
+		header         = make(http.Header)
+		data           []string
+		get, head      bool
+		jsonData       bool
+	)
+	c := s.NewCase("GET", nil)
+	fail := func(format string, v ...interface{}) *Case {
+		ctxErr := NewContextError(format, v...)
+		ctxErr.Prepend("ref", "curl")
+		ctxErr.Append("command", command)
+		return c.fail(ctxErr)
+	}
+
+	words, err := splitShellWords(command)
+	if err != nil {
+		return fail("unable to parse curl command (%s)", err)
+	}
+	if len(words) == 0 || words[0] != "curl" {
+		return fail("not a curl command")
+	}
+	for i := 1; i < len(words); i++ {
+		if expanded := expandShortFlags(words[i]); len(expanded) > 1 {
+			words = append(words[:i], append(expanded, words[i+1:]...)...)
+		}
+		word := words[i]
+		arg := func() (string, bool) {
+			if i+1 >= len(words) {
+				return "", false
+			}
+			i++
+			return words[i], true
+		}
+
+		// options with argument
+		switch word {
+		case "-X", "--request", "-H", "--header", "-d", "--data", "--data-raw",
+			"--data-binary", "--data-ascii", "--data-urlencode", "--json",
+			"-u", "--user", "--url":
+			val, ok := arg()
+			if !ok {
+				return fail("option %s requires an argument", word)
+			}
+			switch word {
+			case "-X", "--request":
+				method = strings.ToUpper(val)
+			case "-H", "--header":
+				parts := strings.SplitN(val, ":", 2)
+				if len(parts) != 2 {
+					return fail("invalid header %#v", val)
+				}
+				header.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+			case "--data-urlencode":
+				if parts := strings.SplitN(val, "=", 2); len(parts) == 2 {
+					val = parts[0] + "=" + url.QueryEscape(parts[1])
+				} else if parts := strings.SplitN(val, "@", 2); len(parts) == 2 {
+					content, err := readCurlData(word, "@"+parts[1])
+					if err != nil {
+						return fail("unable to read data of %s (%s)", word, err)
+					}
+					val = url.QueryEscape(content)
+					if parts[0] != "" {
+						val = parts[0] + "=" + val
+					}
+				} else {
+					val = url.QueryEscape(val)
+				}
+				data = append(data, val)
+			case "--json":
+				if val, err = readCurlData(word, val); err != nil {
+					return fail("unable to read data of %s (%s)", word, err)
+				}
+				jsonData = true
+				data = append(data, val)
+			case "-u", "--user":
+				header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(val)))
+			case "--url":
+				rawURL = val
+			default:
+				if val, err = readCurlData(word, val); err != nil {
+					return fail("unable to read data of %s (%s)", word, err)
+				}
+				data = append(data, val)
+			}
+			continue
+		}
+
+		switch {
+		case word == "-G" || word == "--get":
+			get = true
+		case word == "-I" || word == "--head":
+			head = true
+		case curlFlagsIgnored[word]:
+		case strings.HasPrefix(word, "-"):
+			return fail("unsupported option %s", word)
+		case rawURL == "":
+			rawURL = word
+		default:
+			return fail("unexpected argument %#v", word)
+		}
+	}
+	if rawURL == "" {
+		return fail("no URL in curl command")
+	}
+
+	// resolve the URL against the service
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fail("invalid URL %#v (%s)", rawURL, err)
+	}
+	if !u.IsAbs() {
+		u = s.BaseURL.ResolveReference(u)
+	}
+	body := strings.Join(data, "&")
+	if get && len(data) > 0 {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += body
+		data = nil
+	}
+	if len(s.Query) > 0 {
+		q := u.Query()
+		for key, vals := range s.Query {
+			q[key] = append(q[key], vals...)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	// method defaults as curl does
+	switch {
+	case method != "":
+	case head:
+		method = "HEAD"
+	case len(data) > 0:
+		method = "POST"
+	default:
+		method = "GET"
+	}
+	c.Request.Method = method
+	c.Request.URL = u
+	c.Request.Host = u.Host
+	c.pathTmpl = u.EscapedPath()
+	for key, vals := range header {
+		c.Request.Header[key] = vals
+	}
+
+	if len(data) > 0 {
+		contentType := header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/x-www-form-urlencoded"
+			if jsonData {
+				contentType = "application/json"
+				c.Request.Header.Set("Accept", "application/json")
+			}
+		}
+		if err = c.setBody(body, RawEncoder(contentType)); err != nil {
+			return fail("unable to set body (%s)", err)
+		}
+	}
+	return c
+}
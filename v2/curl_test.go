@@ -0,0 +1,190 @@
+package restit_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestCase_Curl(t *testing.T) {
+	service := restit.NewHTTPService("http://example.com/api")
+	service.AddHeader("X-Request-Id", "abc")
+
+	c := service.Create(map[string]string{"title": "it's"}, "/posts").
+		AddQuery("dry_run", "1")
+	curl, err := c.Curl()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := `curl -X POST 'http://example.com/api/posts?dry_run=1' -H 'Content-Type: application/json' -H 'X-Request-Id: abc' --data-raw '{"title":"it'\''s"}'`,
+		curl; want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+
+	// body is not consumed by rendering
+	if again, _ := c.Curl(); curl != again {
+		t.Errorf("expected %#v, got %#v", curl, again)
+	}
+
+	if want, have := "curl http://example.com/api/post/1", mustCurl(t, service.Retrieve("/post/1").DelHeader("X-Request-Id")); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// relative URL of httptest service is rendered with localhost
+	testService := restit.NewHTTPTestService("/api", http.NotFoundHandler())
+	if want, have := "curl http://localhost/api/post/1", mustCurl(t, testService.Retrieve("/post/1")); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// body read error
+	c = service.Create(nil, "/posts")
+	c.Request.Body = ioutil.NopCloser(iotest.ErrReader(errors.New("dummy read error")))
+	if _, err := c.Curl(); err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "dummy read error", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func mustCurl(t *testing.T, c *restit.Case) string {
+	t.Helper()
+	curl, err := c.Curl()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return curl
+}
+
+func TestCase_CurlOnFailure(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+	service.Auth = restit.BearerAuth("some_token")
+
+	// sensitive headers are redacted by default
+	_, err := service.Retrieve("/post/1").
+		AddHeader("Cookie", "session=secret").
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `curl http://localhost/api/post/1 -H 'Authorization: [REDACTED]' -H 'Cookie: [REDACTED]'`,
+		err.(restit.ContextError).Get("curl"); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+
+	_, err = service.Retrieve("/post/1").
+		ShowSecrets(true).
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `curl http://localhost/api/post/1 -H 'Authorization: Bearer some_token'`,
+		err.(restit.ContextError).Get("curl"); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
+
+func TestFromCurl(t *testing.T) {
+	var method, url, contentType, auth, body string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		method, url, body = r.Method, r.URL.String(), string(data)
+		contentType, auth = r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	if err := ioutil.WriteFile(dataFile, []byte("{\"title\":\n\"from file\"}\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		command     string
+		method      string
+		url         string
+		contentType string
+		auth        string
+		body        string
+	}{
+		{
+			command: `curl /api/post/1`,
+			method:  "GET",
+			url:     "/api/post/1",
+		},
+		{
+			command: `curl -X PUT "/api/post/1?draft=true" \
+  -H 'Content-Type: application/json' \
+  -H "Authorization: Bearer \"token\"" \
+  --data-raw '{"title":"hello world"}'`,
+			method:      "PUT",
+			url:         "/api/post/1?draft=true",
+			contentType: "application/json",
+			auth:        `Bearer "token"`,
+			body:        `{"title":"hello world"}`,
+		},
+		{
+			command:     `curl -s /api/posts -d title=hello -d body=world -u foo:bar`,
+			method:      "POST",
+			url:         "/api/posts",
+			contentType: "application/x-www-form-urlencoded",
+			auth:        "Basic Zm9vOmJhcg==",
+			body:        "title=hello&body=world",
+		},
+		{
+			command: `curl -G /api/posts --data-urlencode 'q=hello world'`,
+			method:  "GET",
+			url:     "/api/posts?q=hello+world",
+		},
+		{
+			command:     `curl -sSXPATCH -HContent-Type:application/json /api/post/1 -d @` + dataFile,
+			method:      "PATCH",
+			url:         "/api/post/1",
+			contentType: "application/json",
+			body:        `{"title":"from file"}`,
+		},
+		{
+			command: `curl -sG /api/posts --data-urlencode q@` + dataFile,
+			method:  "GET",
+			url:     "/api/posts?q=%7B%22title%22%3A%0A%22from+file%22%7D%0A",
+		},
+	}
+	for i, test := range tests {
+		method, url, contentType, auth, body = "", "", "", "", ""
+		if _, err := restit.FromCurl(service, test.command).Do(); err != nil {
+			t.Errorf("test %d: unexpected error: %s", i, err)
+			continue
+		}
+		for _, check := range [][2]string{
+			{test.method, method},
+			{test.url, url},
+			{test.contentType, contentType},
+			{test.auth, auth},
+			{test.body, body},
+		} {
+			if want, have := check[0], check[1]; want != have {
+				t.Errorf("test %d: expected %#v, got %#v", i, want, have)
+			}
+		}
+	}
+
+	// round trip
+	c := service.Create(map[string]string{"title": "it's"}, "/posts")
+	if want, have := mustCurl(t, c), mustCurl(t, restit.FromCurl(service, mustCurl(t, c))); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+
+	// invalid command
+	_, err := restit.FromCurl(service, `curl --proxy localhost /api/posts`).Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `ref="curl" message="unsupported option --proxy" command="curl --proxy localhost /api/posts"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
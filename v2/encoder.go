@@ -0,0 +1,237 @@
+package restit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// BodyEncoder encodes a payload into request body
+type BodyEncoder interface {
+
+	// Encode returns the encoded body and the Content-Type
+	// of the body. A nil body means the request has no body.
+	Encode(payload interface{}) (body io.Reader, contentType string, err error)
+}
+
+// BodyEncoderFunc implements BodyEncoder
+type BodyEncoderFunc func(payload interface{}) (body io.Reader, contentType string, err error)
+
+// Encode implements BodyEncoder
+func (fn BodyEncoderFunc) Encode(payload interface{}) (body io.Reader, contentType string, err error) {
+	return fn(payload)
+}
+
+// JSONEncoder encodes payload with encoding/json
+var JSONEncoder BodyEncoder = BodyEncoderFunc(encodeJSON)
+
+// FormEncoder encodes payload as application/x-www-form-urlencoded.
+// Payload may be url.Values, map[string]string or map[string][]string.
+var FormEncoder BodyEncoder = BodyEncoderFunc(encodeForm)
+
+// XMLEncoder encodes payload with encoding/xml
+var XMLEncoder BodyEncoder = BodyEncoderFunc(encodeXML)
+
+// MultipartEncoder encodes payload as multipart/form-data.
+// Payload may be MultipartForm, *MultipartForm or anything
+// accepted by FormEncoder. The boundary is derived from the
+// content, so the same payload always has the same body.
+var MultipartEncoder BodyEncoder = BodyEncoderFunc(encodeMultipart)
+
+// RawEncoder returns a BodyEncoder which sends the payload
+// as-is with the given Content-Type. Payload may be []byte,
+// string or io.Reader. Default Content-Type is
+// application/octet-stream.
+func RawEncoder(contentType string) BodyEncoder {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return BodyEncoderFunc(func(payload interface{}) (body io.Reader, ct string, err error) {
+		if isNil(payload) {
+			return
+		}
+		switch v := payload.(type) {
+		case []byte:
+			body = bytes.NewReader(v)
+		case string:
+			body = strings.NewReader(v)
+		case io.Reader:
+			body = v
+		default:
+			err = fmt.Errorf("raw encoder does not support payload type %T", payload)
+			return
+		}
+		ct = contentType
+		return
+	})
+}
+
+// MultipartFile represents a file to upload in a
+// multipart/form-data body
+type MultipartFile struct {
+	Field       string
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+// MultipartForm represents the fields and files of
+// a multipart/form-data body
+type MultipartForm struct {
+	Fields url.Values
+	Files  []MultipartFile
+}
+
+// isNil tests if the payload is nil, or a nil pointer, map
+// or slice, which are encoded as no body
+func isNil(payload interface{}) bool {
+	if payload == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(payload); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+func encodeJSON(payload interface{}) (body io.Reader, contentType string, err error) {
+	if isNil(payload) {
+		return
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	return bytes.NewReader(b), "application/json", nil
+}
+
+func encodeXML(payload interface{}) (body io.Reader, contentType string, err error) {
+	if isNil(payload) {
+		return
+	}
+	b, err := xml.Marshal(payload)
+	if err != nil {
+		return
+	}
+	return bytes.NewReader(b), "application/xml", nil
+}
+
+// toValues converts supported form payload types into url.Values
+func toValues(payload interface{}) (values url.Values, err error) {
+	switch v := payload.(type) {
+	case url.Values:
+		values = v
+	case map[string][]string:
+		values = url.Values(v)
+	case map[string]string:
+		values = make(url.Values)
+		for key, val := range v {
+			values.Set(key, val)
+		}
+	default:
+		err = fmt.Errorf("form encoder does not support payload type %T", payload)
+	}
+	return
+}
+
+func encodeForm(payload interface{}) (body io.Reader, contentType string, err error) {
+	if isNil(payload) {
+		return
+	}
+	values, err := toValues(payload)
+	if err != nil {
+		return
+	}
+	return strings.NewReader(values.Encode()), "application/x-www-form-urlencoded", nil
+}
+
+func encodeMultipart(payload interface{}) (body io.Reader, contentType string, err error) {
+	if isNil(payload) {
+		return
+	}
+	var form MultipartForm
+	switch v := payload.(type) {
+	case MultipartForm:
+		form = v
+	case *MultipartForm:
+		form = *v
+	default:
+		if form.Fields, err = toValues(payload); err != nil {
+			err = fmt.Errorf("multipart encoder does not support payload type %T", payload)
+			return
+		}
+	}
+
+	keys := make([]string, 0, len(form.Fields))
+	for key := range form.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	if err = w.SetBoundary(multipartBoundary(form, keys)); err != nil {
+		return
+	}
+	for _, key := range keys {
+		for _, val := range form.Fields[key] {
+			if err = w.WriteField(key, val); err != nil {
+				return
+			}
+		}
+	}
+	for _, file := range form.Files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(file.Field), escapeQuotes(file.Name)))
+		if file.ContentType != "" {
+			h.Set("Content-Type", file.ContentType)
+		} else {
+			h.Set("Content-Type", "application/octet-stream")
+		}
+		var part io.Writer
+		if part, err = w.CreatePart(h); err != nil {
+			return
+		}
+		if _, err = part.Write(file.Content); err != nil {
+			return
+		}
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+	return bytes.NewReader(buf.Bytes()), w.FormDataContentType(), nil
+}
+
+// multipartBoundary returns a boundary derived from the form content,
+// so the same form is always encoded into the same body (e.g. for
+// the Recorder to match it on replay)
+func multipartBoundary(form MultipartForm, keys []string) string {
+	h := sha256.New()
+	for _, key := range keys {
+		for _, val := range form.Fields[key] {
+			fmt.Fprintf(h, "%d:%s%d:%s", len(key), key, len(val), val)
+		}
+	}
+	for _, file := range form.Files {
+		fmt.Fprintf(h, "%d:%s%d:%s%d:%s%d:", len(file.Field), file.Field,
+			len(file.Name), file.Name, len(file.ContentType), file.ContentType, len(file.Content))
+		h.Write(file.Content)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:32]
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
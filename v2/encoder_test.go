@@ -0,0 +1,271 @@
+package restit_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestNewRequest_NilPayload(t *testing.T) {
+	req, err := restit.NewRequest("GET", "/foo/bar", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if req.Body != nil {
+		t.Errorf("expected nil body, got %#v", req.Body)
+	}
+	if want, have := "", req.Header.Get("Content-Type"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestNewRequestWith_TypedNilPayload(t *testing.T) {
+	type post struct {
+		ID string `json:"id"`
+	}
+	for _, test := range []struct {
+		enc     restit.BodyEncoder
+		payload interface{}
+	}{
+		{restit.JSONEncoder, (*post)(nil)},
+		{restit.JSONEncoder, map[string]string(nil)},
+		{restit.JSONEncoder, []string(nil)},
+		{restit.XMLEncoder, (*post)(nil)},
+		{restit.FormEncoder, url.Values(nil)},
+		{restit.MultipartEncoder, (*restit.MultipartForm)(nil)},
+		{restit.RawEncoder(""), []byte(nil)},
+	} {
+		req, err := restit.NewRequestWith("POST", "/foo/bar", test.payload, test.enc)
+		if err != nil {
+			t.Fatalf("%T: unexpected error: %s", test.payload, err)
+		}
+		if req.Body != nil {
+			t.Errorf("%T: expected nil body, got %#v", test.payload, req.Body)
+		}
+		if want, have := "", req.Header.Get("Content-Type"); want != have {
+			t.Errorf("%T: expected %#v, got %#v", test.payload, want, have)
+		}
+	}
+}
+
+func TestNewRequestWith(t *testing.T) {
+	type post struct {
+		ID string `json:"id" xml:"id"`
+	}
+
+	tests := []struct {
+		desc        string
+		enc         restit.BodyEncoder
+		payload     interface{}
+		contentType string
+		body        string
+	}{
+		{
+			desc:        "json",
+			enc:         restit.JSONEncoder,
+			payload:     post{ID: "hello"},
+			contentType: "application/json",
+			body:        `{"id":"hello"}`,
+		},
+		{
+			desc:        "form",
+			enc:         restit.FormEncoder,
+			payload:     url.Values{"user": {"foo"}, "pass": {"bar"}},
+			contentType: "application/x-www-form-urlencoded",
+			body:        "pass=bar&user=foo",
+		},
+		{
+			desc:        "form with map",
+			enc:         restit.FormEncoder,
+			payload:     map[string]string{"user": "foo"},
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user=foo",
+		},
+		{
+			desc:        "xml",
+			enc:         restit.XMLEncoder,
+			payload:     post{ID: "hello"},
+			contentType: "application/xml",
+			body:        `<post><id>hello</id></post>`,
+		},
+		{
+			desc:        "raw bytes",
+			enc:         restit.RawEncoder(""),
+			payload:     []byte("hello raw"),
+			contentType: "application/octet-stream",
+			body:        "hello raw",
+		},
+		{
+			desc:        "raw reader",
+			enc:         restit.RawEncoder("text/plain"),
+			payload:     strings.NewReader("hello reader"),
+			contentType: "text/plain",
+			body:        "hello reader",
+		},
+	}
+
+	for _, test := range tests {
+		req, err := restit.NewRequestWith("POST", "/foo/bar", test.payload, test.enc)
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %s", test.desc, err)
+			continue
+		}
+		if want, have := test.contentType, req.Header.Get("Content-Type"); want != have {
+			t.Errorf("[%s] expected %#v, got %#v", test.desc, want, have)
+		}
+		if b, err := ioutil.ReadAll(req.Body); err != nil {
+			t.Errorf("[%s] unexpected error: %s", test.desc, err)
+		} else if want, have := test.body, string(b); want != have {
+			t.Errorf("[%s] expected %#v, got %#v", test.desc, want, have)
+		}
+	}
+}
+
+func TestNewRequestWith_Unsupported(t *testing.T) {
+	if _, err := restit.NewRequestWith("POST", "/foo/bar", 42, restit.FormEncoder); err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "form encoder does not support payload type int", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestNewRequestWith_MultipartBoundary(t *testing.T) {
+	encode := func(content string) string {
+		body, contentType, err := restit.MultipartEncoder.Encode(restit.MultipartForm{
+			Fields: url.Values{"title": {"hello"}},
+			Files:  []restit.MultipartFile{{Field: "f", Name: "a.txt", Content: []byte(content)}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		b, _ := ioutil.ReadAll(body)
+		return contentType + "\n" + string(b)
+	}
+	if want, have := encode("foo"), encode("foo"); want != have {
+		t.Errorf("expected the same form to be encoded the same\nexpected: %s\ngot:      %s", want, have)
+	}
+	if encode("foo") == strings.Replace(encode("bar"), "bar", "foo", 1) {
+		t.Errorf("expected different boundaries for different forms")
+	}
+}
+
+func TestNewRequestWith_Multipart(t *testing.T) {
+	form := restit.MultipartForm{
+		Fields: url.Values{"title": {"hello"}},
+		Files: []restit.MultipartFile{
+			{Field: "avatar", Name: "avatar.png", Content: []byte("dummy image")},
+		},
+	}
+	req, err := restit.NewRequestWith("POST", "/upload", form, restit.MultipartEncoder)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := req.ParseMultipartForm(1024); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "hello", req.FormValue("title"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	file, header, err := req.FormFile("avatar")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer file.Close()
+	if want, have := "avatar.png", header.Filename; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if b, _ := ioutil.ReadAll(file); string(b) != "dummy image" {
+		t.Errorf("expected %#v, got %#v", "dummy image", string(b))
+	}
+}
+
+func TestService_Encoder(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+		w.Write([]byte(r.PostForm.Get("user")))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+	service.Encoder = restit.FormEncoder
+
+	resp, err := service.Create(url.Values{"user": {"foo"}}, "login").Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "foo", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// per case override
+	resp, err = service.Create(map[string]string{"user": "bar"}, "login").
+		WithEncoder(restit.JSONEncoder).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "application/json", resp.Header().Get("X-Content-Type"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// encoder error is returned by Do
+	service.Encoder = nil
+	if _, err = service.Create(42, "login").
+		WithEncoder(restit.RawEncoder("")).
+		Do(); err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "raw encoder does not support payload type int", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestService_EncoderOverride(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+	service.Encoder = restit.FormEncoder
+	type login struct {
+		User string `json:"user"`
+	}
+	payload := login{"foo"}
+
+	// payload not supported by the service encoder
+	if _, err := service.Create(payload, "login").Do(); err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "form encoder does not support payload type restit_test.login", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// overridden by case encoder
+	resp, err := service.Create(payload, "login").
+		WithEncoder(restit.JSONEncoder).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := `{"user":"foo"}`, resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestHTTPTestHandler_NilBody(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Body = nil
+	resp, err := restit.HTTPTestHandler(handler)(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
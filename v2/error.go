@@ -1,6 +1,7 @@
 package restit
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -43,14 +44,104 @@ type keyval struct {
 	weight int
 }
 
-// ExpandError expands errors to ContextError
+// ExpandError expands errors to ContextError. It returns err as-is
+// if it implements ContextError, or wraps the error in a new one.
 func ExpandError(err error) ContextError {
-	switch ctxErr := err.(type) {
-	case *contextError:
-		return ctxErr
-	default:
-		return NewContextError(err.Error())
+	if cErr, ok := err.(ContextError); ok {
+		return cErr
 	}
+	return &causeError{NewContextError("%s", err.Error()), err}
+}
+
+// causeError is a ContextError wrapping the original error
+type causeError struct {
+	ContextError
+	cause error
+}
+
+// Unwrap returns the original error
+func (err *causeError) Unwrap() error {
+	return err.cause
+}
+
+// MultiError is the ContextError of multiple failures
+// (e.g. all failed expectations of a case in soft assert mode)
+type MultiError struct {
+	Errors []ContextError
+}
+
+// Append implements ContextError. It appends the key-value
+// pair to all errors.
+func (m *MultiError) Append(key string, value interface{}) {
+	for _, err := range m.Errors {
+		err.Append(key, value)
+	}
+}
+
+// Prepend implements ContextError. It prepends the key-value
+// pair to all errors.
+func (m *MultiError) Prepend(key string, value interface{}) {
+	for _, err := range m.Errors {
+		err.Prepend(key, value)
+	}
+}
+
+// Get implements ContextError. It returns the value of the
+// first error.
+func (m *MultiError) Get(key string) (value interface{}) {
+	if len(m.Errors) > 0 {
+		value = m.Errors[0].Get(key)
+	}
+	return
+}
+
+// Delete implements ContextError. It deletes the key from
+// all errors.
+func (m *MultiError) Delete(key string) {
+	for _, err := range m.Errors {
+		err.Delete(key)
+	}
+}
+
+// Log implements ContextError. It returns the log of every
+// error, one per line.
+func (m *MultiError) Log() string {
+	logs := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		logs[i] = err.Log()
+	}
+	return strings.Join(logs, "\n")
+}
+
+// Error implements ContextError
+func (m *MultiError) Error() string {
+	msgs := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d failures: %s", len(m.Errors), strings.Join(msgs, "; "))
+}
+
+// Is reports if any of the errors matches target.
+// Implements errors.Is support.
+func (m *MultiError) Is(target error) bool {
+	for _, err := range m.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target.
+// Implements errors.As support.
+func (m *MultiError) As(target interface{}) bool {
+	for _, err := range m.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
 }
 
 // contextError is the default implementation of ContextError
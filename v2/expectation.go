@@ -1,10 +1,11 @@
 package restit
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/go-restit/lzjson"
-	"golang.org/x/net/context"
 )
 
 // StatusCodeIs test the response status code
@@ -21,7 +22,13 @@ func StatusCodeIs(n int) Expectation {
 		})
 }
 
-// LengthIs test the length of a given field
+// LengthIs test the length of a given field.
+//
+// The field is a JSON path with keys, indexes (e.g. "data.items",
+// "posts[0].tags" or "posts.0.tags"), wildcards (e.g. "posts[*].tags")
+// and filters (e.g. `items[?(@.status=="active")]`, with ==, !=, <,
+// <=, >, >= or existence test like "items[?(@.tags)]"). If the path
+// has wildcard or filter, the number of matches is tested instead.
 func LengthIs(name string, n int) Expectation {
 	return Describe(
 		fmt.Sprintf("length of %#v is %d", name, n),
@@ -29,27 +36,30 @@ func LengthIs(name string, n int) Expectation {
 			proto, err := resp.JSON()
 			if err != nil {
 				return
-			} else if want, have := lzjson.TypeObject, proto.Type(); want != have {
-				ctxErr := NewContextError("expected root to be type %s, got %s", want, have)
-				ctxErr.Prepend("ref", "response")
-				err = ctxErr
-				return
 			}
 
-			list := proto.Get(name)
-			if want, have := lzjson.TypeArray, list.Type(); want != have {
-				ctxErr := NewContextError("expected %#v to be type %s, got %s",
-					name, want, have)
-				ctxErr.Prepend("ref", "response."+name)
-				ctxErr.Append("response", string(proto.Raw()))
-				err = ctxErr
-				return
+			matches, definite, pathErr := evalPath(proto, name)
+			if pathErr != nil {
+				return pathErr
+			}
+			have := len(matches)
+			if definite {
+				list := matches[0]
+				if want, have := lzjson.TypeArray, list.node.Type(); want != have {
+					ctxErr := NewContextError("expected %#v to be type %s, got %s",
+						name, want, have)
+					ctxErr.Prepend("ref", "response"+list.path)
+					ctxErr.Append("response", string(proto.Raw()))
+					err = ctxErr
+					return
+				}
+				have = list.node.Len()
 			}
 
-			if want, have := n, list.Len(); want != have {
+			if want := n; want != have {
 				ctxErr := NewContextError("expected %#v to be length %#v, got %#v",
 					name, want, have)
-				ctxErr.Prepend("ref", "response."+name+".length")
+				ctxErr.Prepend("ref", "response."+strings.TrimPrefix(name, "$.")+".length")
 				err = ctxErr
 				return
 			}
@@ -71,7 +81,9 @@ func Nth(n int) *NthTest {
 	return &NthTest{n: n, tests: make([]JSONTest, 0)}
 }
 
-// Of specify the field which should be an array
+// Of specify the field which should be an array. The field is
+// a JSON path (see LengthIs). If the path has wildcard or filter,
+// the nth match is tested instead.
 func (t *NthTest) Of(name string) *NthTest {
 	t.name = name
 	return t
@@ -97,23 +109,31 @@ func (t *NthTest) Do(ctx context.Context, resp Response) (err error) {
 		return
 	}
 
-	field := root.Get(t.name)
-	if want, have := lzjson.TypeArray, field.Type(); want != have {
-		if have == lzjson.TypeUndefined {
-			err = fmt.Errorf("field %#v undefined", t.name)
-		} else {
+	matches, definite, pathErr := evalPath(root, t.name)
+	if pathErr != nil {
+		err = pathErr
+		return
+	}
+	var nth lzjson.Node
+	if definite {
+		field := matches[0].node
+		if want, have := lzjson.TypeArray, field.Type(); want != have {
 			err = fmt.Errorf("field %#v is not an array, is %s (%s)",
 				t.name, field.Type(), field.Raw())
+			return
 		}
-		return
-	}
-
-	if field.Len() <= int(t.n) {
-		err = fmt.Errorf("%s does not have item %d", t.name, t.n)
-		return
+		if field.Len() <= int(t.n) {
+			err = fmt.Errorf("%s does not have item %d", t.name, t.n)
+			return
+		}
+		nth = field.GetN(int(t.n))
+	} else {
+		if len(matches) <= int(t.n) {
+			err = fmt.Errorf("%s does not have match %d", t.name, t.n)
+			return
+		}
+		nth = matches[t.n].node
 	}
-
-	nth := field.GetN(int(t.n))
 	for _, test := range t.tests {
 		if err = test.Do(nth); err != nil {
 			err = fmt.Errorf("failed \"%s\" (%s)", test.Desc(), err)
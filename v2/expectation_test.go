@@ -1,6 +1,7 @@
 package restit_test
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,7 +13,6 @@ import (
 
 	"github.com/go-restit/lzjson"
 	"github.com/go-restit/restit/v2"
-	"golang.org/x/net/context"
 )
 
 func init() {
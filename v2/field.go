@@ -0,0 +1,220 @@
+package restit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-restit/lzjson"
+)
+
+// typeName returns the JSON Schema name of the lzjson.Type
+func typeName(t lzjson.Type) string {
+	switch t {
+	case lzjson.TypeString:
+		return "string"
+	case lzjson.TypeNumber:
+		return "number"
+	case lzjson.TypeObject:
+		return "object"
+	case lzjson.TypeArray:
+		return "array"
+	case lzjson.TypeBool:
+		return "boolean"
+	case lzjson.TypeNull:
+		return "null"
+	}
+	return "undefined"
+}
+
+// toJSON returns the JSON encoding of the value for
+// comparison and error messages
+func toJSON(v interface{}) ([]byte, error) {
+	switch raw := v.(type) {
+	case json.RawMessage:
+		return raw, nil
+	case lzjson.Node:
+		return bytes.TrimSpace(raw.Raw()), nil
+	}
+	return json.Marshal(v)
+}
+
+// fieldError returns the ContextError of the failed field
+func fieldError(m pathMatch, expected string, format string, v ...interface{}) ContextError {
+	ctxErr := NewContextError(format, v...)
+	ctxErr.Prepend("ref", "response"+m.path)
+	if expected != "" {
+		ctxErr.Append("expected", expected)
+	}
+	ctxErr.Append("actual", string(bytes.TrimSpace(m.node.Raw())))
+	return ctxErr
+}
+
+// field returns an Expectation which tests every node matched by
+// the JSON path. The path must match at least one node.
+func field(desc, path string, test func(m pathMatch) error) Expectation {
+	return Describe(desc, func(ctx context.Context, resp Response) (err error) {
+		root, err := resp.JSON()
+		if err != nil {
+			return
+		}
+		matches, _, pathErr := evalPath(root, path)
+		if pathErr != nil {
+			return pathErr
+		}
+		if len(matches) == 0 {
+			ctxErr := NewContextError("no field matches %#v", path)
+			ctxErr.Prepend("ref", "response")
+			return ctxErr
+		}
+		for _, m := range matches {
+			if err = test(m); err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
+// FieldEquals tests if the field of the JSON path (see LengthIs)
+// equals the JSON encoding of v. Object keys order and number
+// formatting are ignored. If the path has wildcard or filter, every
+// match is tested.
+func FieldEquals(path string, v interface{}) Expectation {
+	expected, encErr := toJSON(v)
+	return field(
+		fmt.Sprintf("field %#v equals %s", path, expected),
+		path,
+		func(m pathMatch) error {
+			if encErr != nil {
+				return fieldError(m, "", "unable to encode expected value (%s)", encErr)
+			}
+			if !jsonEqual(expected, m.node.Raw()) {
+				return fieldError(m, string(expected), "expected %#v to equal %s, got %s",
+					path, expected, bytes.TrimSpace(m.node.Raw()))
+			}
+			return nil
+		})
+}
+
+// FieldMatches tests if the string field of the JSON path
+// (see LengthIs) matches the regular expression
+func FieldMatches(path string, re *regexp.Regexp) Expectation {
+	return field(
+		fmt.Sprintf("field %#v matches /%s/", path, re),
+		path,
+		func(m pathMatch) error {
+			if m.node.Type() != lzjson.TypeString {
+				return fieldError(m, "/"+re.String()+"/", "expected %#v to be string, got %s",
+					path, typeName(m.node.Type()))
+			}
+			if !re.MatchString(m.node.String()) {
+				return fieldError(m, "/"+re.String()+"/", "expected %#v to match /%s/, got %#v",
+					path, re, m.node.String())
+			}
+			return nil
+		})
+}
+
+// FieldType tests the type of the field of the JSON path
+// (see LengthIs)
+func FieldType(path string, t lzjson.Type) Expectation {
+	return field(
+		fmt.Sprintf("field %#v is %s", path, typeName(t)),
+		path,
+		func(m pathMatch) error {
+			if have := m.node.Type(); have != t {
+				return fieldError(m, typeName(t), "expected %#v to be %s, got %s",
+					path, typeName(t), typeName(have))
+			}
+			return nil
+		})
+}
+
+// FieldExists tests if the JSON path (see LengthIs) matches
+// any field
+func FieldExists(path string) Expectation {
+	return field(
+		fmt.Sprintf("field %#v exists", path),
+		path,
+		func(m pathMatch) error { return nil })
+}
+
+// FieldAbsent tests if the JSON path (see LengthIs) matches
+// no field. Fields with null value are present. A path which
+// cannot apply to the response (e.g. key of a string) fails.
+func FieldAbsent(path string) Expectation {
+	return Describe(
+		fmt.Sprintf("field %#v is absent", path),
+		func(ctx context.Context, resp Response) (err error) {
+			parsed, err := parseJSONPath(path)
+			if err != nil {
+				ctxErr := NewContextError("%s", err)
+				ctxErr.Prepend("ref", "response")
+				return ctxErr
+			}
+			root, err := resp.JSON()
+			if err != nil {
+				return
+			}
+			matches, evalErr := evalSegments(root, parsed.segments, path)
+			if evalErr != nil {
+				if pathErr := evalErr.(*pathError); !pathErr.missing {
+					return pathErr.ContextError()
+				}
+				return nil
+			}
+			if len(matches) == 0 {
+				return nil
+			}
+			return fieldError(matches[0], "", "expected %#v to be absent", path)
+		})
+}
+
+// FieldIsNull tests if the field of the JSON path (see LengthIs)
+// is null
+func FieldIsNull(path string) Expectation {
+	return field(
+		fmt.Sprintf("field %#v is null", path),
+		path,
+		func(m pathMatch) error {
+			if !m.node.IsNull() {
+				return fieldError(m, "null", "expected %#v to be null, got %s",
+					path, bytes.TrimSpace(m.node.Raw()))
+			}
+			return nil
+		})
+}
+
+// FieldIn tests if the field of the JSON path (see LengthIs)
+// equals the JSON encoding of any of the values
+func FieldIn(path string, values ...interface{}) Expectation {
+	expected := make([][]byte, len(values))
+	strs := make([]string, len(values))
+	var encErr error
+	for i, v := range values {
+		if expected[i], encErr = toJSON(v); encErr != nil {
+			break
+		}
+		strs[i] = string(expected[i])
+	}
+	list := "[" + strings.Join(strs, ", ") + "]"
+	return field(
+		fmt.Sprintf("field %#v is one of %s", path, list),
+		path,
+		func(m pathMatch) error {
+			if encErr != nil {
+				return fieldError(m, "", "unable to encode expected value (%s)", encErr)
+			}
+			for _, value := range expected {
+				if jsonEqual(value, m.node.Raw()) {
+					return nil
+				}
+			}
+			return fieldError(m, list, "expected %#v to be one of %s, got %s",
+				path, list, bytes.TrimSpace(m.node.Raw()))
+		})
+}
@@ -0,0 +1,144 @@
+package restit_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/go-restit/lzjson"
+	restit "github.com/go-restit/restit/v2"
+)
+
+const dummyFieldJSON = `{
+	"id": "post-1",
+	"title": "hello",
+	"score": 10.0,
+	"draft": false,
+	"deleted": null,
+	"author": {"name": "alice", "roles": ["admin", "editor"]},
+	"comments": [{"id": 1, "ok": true}, {"id": 2, "ok": true}]
+}`
+
+func TestField_Desc(t *testing.T) {
+	tests := []struct {
+		exp  restit.Expectation
+		desc string
+	}{
+		{restit.FieldEquals("title", "hello"), `field "title" equals "hello"`},
+		{restit.FieldMatches("id", regexp.MustCompile(`^post-\d+$`)), `field "id" matches /^post-\d+$/`},
+		{restit.FieldType("author", lzjson.TypeObject), `field "author" is object`},
+		{restit.FieldType("draft", lzjson.TypeBool), `field "draft" is boolean`},
+		{restit.FieldExists("author.name"), `field "author.name" exists`},
+		{restit.FieldAbsent("author.email"), `field "author.email" is absent`},
+		{restit.FieldIsNull("deleted"), `field "deleted" is null`},
+		{restit.FieldIn("score", 10, 20), `field "score" is one of [10, 20]`},
+	}
+	for _, test := range tests {
+		if want, have := test.desc, test.exp.Desc(); want != have {
+			t.Errorf("expected %#v, got %#v", want, have)
+		}
+	}
+}
+
+func TestField_Pass(t *testing.T) {
+	ctx := context.Background()
+	resp := jsonResponse(dummyFieldJSON)
+
+	tests := []restit.Expectation{
+		restit.FieldEquals("title", "hello"),
+		restit.FieldEquals("score", 10),
+		restit.FieldEquals("author", map[string]interface{}{
+			"roles": []string{"admin", "editor"},
+			"name":  "alice",
+		}),
+		restit.FieldEquals("comments[*].ok", true),
+		restit.FieldMatches("id", regexp.MustCompile(`^post-\d+$`)),
+		restit.FieldMatches("author.roles[*]", regexp.MustCompile(`^[a-z]+$`)),
+		restit.FieldType("draft", lzjson.TypeBool),
+		restit.FieldType("comments[*].id", lzjson.TypeNumber),
+		restit.FieldExists("author.roles[1]"),
+		restit.FieldExists("deleted"),
+		restit.FieldAbsent("author.email"),
+		restit.FieldAbsent("comments[5]"),
+		restit.FieldAbsent(`comments[?(@.id==3)]`),
+		restit.FieldIsNull("deleted"),
+		restit.FieldIn("author.name", "alice", "bob"),
+		restit.FieldIn("comments[*].id", 1, 2),
+	}
+	for _, test := range tests {
+		if err := test.Do(ctx, resp); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.Desc(), err)
+		}
+	}
+}
+
+func TestField_Fail(t *testing.T) {
+	ctx := context.Background()
+	resp := jsonResponse(dummyFieldJSON)
+
+	tests := []struct {
+		exp restit.Expectation
+		log string
+	}{
+		{
+			exp: restit.FieldEquals("title", "world"),
+			log: `ref="response.title" message="expected \"title\" to equal \"world\", got \"hello\"" expected="\"world\"" actual="\"hello\""`,
+		},
+		{
+			exp: restit.FieldEquals("comments[*].id", 1),
+			log: `ref="response.comments[1].id" message="expected \"comments[*].id\" to equal 1, got 2" expected="1" actual="2"`,
+		},
+		{
+			exp: restit.FieldMatches("score", regexp.MustCompile(`^\d+$`)),
+			log: `ref="response.score" message="expected \"score\" to be string, got number" expected="/^\\d+$/" actual="10.0"`,
+		},
+		{
+			exp: restit.FieldMatches("author.name", regexp.MustCompile(`^b`)),
+			log: `ref="response.author.name" message="expected \"author.name\" to match /^b/, got \"alice\"" expected="/^b/" actual="\"alice\""`,
+		},
+		{
+			exp: restit.FieldType("author.roles", lzjson.TypeObject),
+			log: `ref="response.author.roles" message="expected \"author.roles\" to be object, got array" expected="object" actual="[\"admin\", \"editor\"]"`,
+		},
+		{
+			exp: restit.FieldExists("author.email"),
+			log: `ref="response.author" message="unable to resolve path \"author.email\" (key \"email\" not found)"`,
+		},
+		{
+			exp: restit.FieldExists(`comments[?(@.id==3)]`),
+			log: `ref="response" message="no field matches \"comments[?(@.id==3)]\""`,
+		},
+		{
+			exp: restit.FieldAbsent("deleted"),
+			log: `ref="response.deleted" message="expected \"deleted\" to be absent" actual="null"`,
+		},
+		{
+			exp: restit.FieldAbsent("title.foo"),
+			log: `ref="response.title" message="unable to resolve path \"title.foo\" (expected object for key \"foo\", got string)"`,
+		},
+		{
+			exp: restit.FieldType("draft", lzjson.TypeString),
+			log: `ref="response.draft" message="expected \"draft\" to be string, got boolean" expected="string" actual="false"`,
+		},
+		{
+			exp: restit.FieldAbsent("comments[?(@.id=="),
+			log: `ref="response" message="invalid path \"comments[?(@.id==\" (unterminated bracket at offset 8)"`,
+		},
+		{
+			exp: restit.FieldIsNull("draft"),
+			log: `ref="response.draft" message="expected \"draft\" to be null, got false" expected="null" actual="false"`,
+		},
+		{
+			exp: restit.FieldIn("author.name", "bob", "carol"),
+			log: `ref="response.author.name" message="expected \"author.name\" to be one of [\"bob\", \"carol\"], got \"alice\"" expected="[\"bob\", \"carol\"]" actual="\"alice\""`,
+		},
+	}
+	for _, test := range tests {
+		err := test.exp.Do(ctx, resp)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", test.exp.Desc())
+		} else if want, have := test.log, err.(restit.ContextError).Log(); want != have {
+			t.Errorf("%s\nexpected: %s\ngot:      %s", test.exp.Desc(), want, have)
+		}
+	}
+}
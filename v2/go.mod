@@ -5,5 +5,4 @@ go 1.18
 require (
 	github.com/go-restit/lzjson v0.0.0-20161206095556-efe3c53acc68
 	github.com/gorilla/mux v1.8.1
-	golang.org/x/net v0.23.0
 )
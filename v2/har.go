@@ -0,0 +1,275 @@
+package restit
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// HAR implements Observer and collects the requests and
+// responses as HAR 1.2 (HTTP Archive) entries, which can be
+// opened in browser devtools and other HAR viewers.
+//
+// Use one HAR per test (see WriteOnCleanup) or share one
+// among the tests of a suite (see WriteFile).
+type HAR struct {
+
+	// RedactHeader, if not nil, returns the recorded value
+	// of a request or response header
+	RedactHeader func(name, value string) string
+
+	// RedactBody, if not nil, returns the recorded request
+	// or response body of the content type
+	RedactBody func(contentType, body string) string
+
+	mutex   sync.Mutex
+	entries []harEntry
+}
+
+// NewHAR creates an empty HAR which redacts the SensitiveHeaders
+func NewHAR() *HAR {
+	return &HAR{RedactHeader: RedactSensitiveHeader}
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+// milliseconds returns the duration in milliseconds, or -1
+// if the phase does not apply
+func milliseconds(d time.Duration) float64 {
+	if d <= 0 {
+		return -1
+	}
+	return float64(d) / float64(time.Millisecond)
+}
+
+// headers returns the sorted and redacted name-value pairs
+func (h *HAR) headers(header http.Header) (pairs []harNameValue) {
+	pairs = []harNameValue{}
+	for name, vals := range header {
+		for _, val := range vals {
+			if h.RedactHeader != nil {
+				val = h.RedactHeader(name, val)
+			}
+			pairs = append(pairs, harNameValue{name, val})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Name < pairs[j].Name
+	})
+	return
+}
+
+// body returns the redacted body
+func (h *HAR) body(contentType, body string) string {
+	if h.RedactBody != nil {
+		return h.RedactBody(contentType, body)
+	}
+	return body
+}
+
+// Observe implements Observer
+func (h *HAR) Observe(req *http.Request, resp Response) {
+	now := time.Now()
+	entry := harEntry{
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         absoluteURL(req),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     h.headers(req.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+		},
+		Response: harResponse{
+			Status:      resp.StatusCode(),
+			StatusText:  http.StatusText(resp.StatusCode()),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     h.headers(resp.Header()),
+			RedirectURL: resp.Header().Get("Location"),
+			HeadersSize: -1,
+		},
+		Timings: harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+
+	// request query and body
+	for key, vals := range req.URL.Query() {
+		for _, val := range vals {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{key, val})
+		}
+	}
+	sort.SliceStable(entry.Request.QueryString, func(i, j int) bool {
+		return entry.Request.QueryString[i].Name < entry.Request.QueryString[j].Name
+	})
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(body)
+			body.Close()
+			contentType := req.Header.Get("Content-Type")
+			entry.Request.BodySize = len(data)
+			entry.Request.PostData = &harPostData{
+				MimeType: contentType,
+				Text:     h.body(contentType, string(data)),
+			}
+		}
+	}
+
+	// response body
+	body := resp.String()
+	contentType := resp.Header().Get("Content-Type")
+	entry.Response.BodySize = len(body)
+	entry.Response.Content = harContent{
+		Size:     len(body),
+		MimeType: contentType,
+		Text:     h.body(contentType, body),
+	}
+
+	// timings, if available
+	started := now
+	if timing := TimingOf(resp); timing != nil {
+		started = now.Add(-timing.Total)
+		entry.Time = float64(timing.Total) / float64(time.Millisecond)
+		entry.Timings.DNS = milliseconds(timing.DNS)
+		entry.Timings.Connect = milliseconds(timing.Connect)
+		entry.Timings.SSL = milliseconds(timing.TLS)
+		entry.Timings.Wait = entry.Time
+		if timing.FirstByte > 0 {
+			entry.Timings.Wait = float64(timing.FirstByte) / float64(time.Millisecond)
+			entry.Timings.Receive = entry.Time - entry.Timings.Wait
+		}
+	}
+	entry.StartedDateTime = started.Format(time.RFC3339Nano)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.entries = append(h.entries, entry)
+}
+
+// Len returns the number of entries collected
+func (h *HAR) Len() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.entries)
+}
+
+// Reset removes all the entries collected
+func (h *HAR) Reset() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.entries = nil
+}
+
+// Write writes the entries collected as HAR 1.2 JSON
+func (h *HAR) Write(w io.Writer) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var doc struct {
+		Log struct {
+			Version string `json:"version"`
+			Creator struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"creator"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	doc.Log.Version = "1.2"
+	doc.Log.Creator.Name = "restit"
+	doc.Log.Creator.Version = "2"
+	doc.Log.Entries = append([]harEntry{}, h.entries...)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// WriteFile writes the entries collected to the file path
+func (h *HAR) WriteFile(path string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	if err = h.Write(f); err != nil {
+		f.Close()
+		return
+	}
+	return f.Close()
+}
+
+// WriteOnCleanup writes the entries collected to the file path
+// when the test and its subtests complete. If onlyFailed is true,
+// the file is written only if the test failed.
+func (h *HAR) WriteOnCleanup(t testing.TB, path string, onlyFailed bool) {
+	t.Cleanup(func() {
+		if onlyFailed && !t.Failed() {
+			return
+		}
+		if err := h.WriteFile(path); err != nil {
+			t.Errorf("unable to write HAR file %#v (%s)", path, err)
+		}
+	})
+}
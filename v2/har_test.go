@@ -0,0 +1,137 @@
+package restit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestHAR(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+	har := restit.NewHAR()
+	har.RedactHeader = func(name, value string) string {
+		if name == "Authorization" {
+			return "[REDACTED]"
+		}
+		return value
+	}
+	har.RedactBody = func(contentType, body string) string {
+		return strings.Replace(body, "secret", "[REDACTED]", -1)
+	}
+
+	service := restit.NewHTTPTestService("/api", handler)
+	service.Observers = []restit.Observer{har}
+	service.Auth = restit.BearerAuth("some_token")
+
+	_, err := service.Create(map[string]string{"password": "secret"}, "/posts").
+		AddQuery("dry_run", "1").
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := 1, har.Len(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	var buf bytes.Buffer
+	if err = har.Write(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "some_token") {
+		t.Errorf("secrets are not redacted:\n%s", buf.String())
+	}
+
+	var doc struct {
+		Log struct {
+			Version string `json:"version"`
+			Entries []struct {
+				Time    float64 `json:"time"`
+				Request struct {
+					Method      string `json:"method"`
+					URL         string `json:"url"`
+					QueryString []struct {
+						Name  string `json:"name"`
+						Value string `json:"value"`
+					} `json:"queryString"`
+					PostData struct {
+						Text string `json:"text"`
+					} `json:"postData"`
+				} `json:"request"`
+				Response struct {
+					Status  int `json:"status"`
+					Content struct {
+						MimeType string `json:"mimeType"`
+						Text     string `json:"text"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err = json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "1.2", doc.Log.Version; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	entry := doc.Log.Entries[0]
+	if want, have := "POST http://localhost/api/posts?dry_run=1", entry.Request.Method+" "+entry.Request.URL; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := "dry_run", entry.Request.QueryString[0].Name; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := `{"password":"[REDACTED]"}`, strings.TrimSpace(entry.Request.PostData.Text); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := http.StatusOK, entry.Response.Status; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := "application/json", entry.Response.Content.MimeType; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := `{"password":"[REDACTED]"}`, strings.TrimSpace(entry.Response.Content.Text); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if entry.Time <= 0 {
+		t.Errorf("expected time, got %#v", entry.Time)
+	}
+
+	har.Reset()
+	if want, have := 0, har.Len(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestHAR_WriteOnCleanup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.har")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	t.Run("case", func(t *testing.T) {
+		har := restit.NewHAR()
+		har.WriteOnCleanup(t, path, false)
+		service := restit.NewHTTPTestService("/api", handler)
+		if _, err := service.Retrieve("/post/1").Observe(har).Do(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := `"text": "hello"`, string(data); !strings.Contains(have, want) {
+		t.Errorf("expected %s in:\n%s", want, have)
+	}
+}
@@ -0,0 +1,172 @@
+package restit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPOption configures the http.Client of NewHTTPService
+type HTTPOption func(opts *httpOptions)
+
+// httpOptions stores all the HTTPOption applied
+type httpOptions struct {
+	client        *http.Client
+	transport     http.RoundTripper
+	timeout       time.Duration
+	certificates  []tls.Certificate
+	rootCAs       *x509.CertPool
+	checkRedirect func(req *http.Request, via []*http.Request) error
+	hosts         map[string]string
+}
+
+// WithHTTPClient uses the given client as base. The client
+// is copied so it will not be modified by other options.
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.client = client
+	}
+}
+
+// WithTransport uses the given RoundTripper as client transport
+func WithTransport(transport http.RoundTripper) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.transport = transport
+	}
+}
+
+// WithClientTimeout sets the timeout of the client
+func WithClientTimeout(timeout time.Duration) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// WithClientCertificates sets the client certificates for
+// mutual TLS authentication
+func WithClientCertificates(certs ...tls.Certificate) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.certificates = append(opts.certificates, certs...)
+	}
+}
+
+// WithRootCAs sets the root certificate authorities to
+// verify server certificates with
+func WithRootCAs(pool *x509.CertPool) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.rootCAs = pool
+	}
+}
+
+// WithRedirectPolicy sets the CheckRedirect function of the client.
+// Use NoRedirect to receive the redirect responses as-is.
+func WithRedirectPolicy(checkRedirect func(req *http.Request, via []*http.Request) error) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.checkRedirect = checkRedirect
+	}
+}
+
+// NoRedirect is a redirect policy which stops following redirects
+func NoRedirect(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// WithHostOverride connects to the mapped address instead of the
+// resolved host. Keys are "host:port" or "host" (any port), values
+// are "address:port" or "address" (keeping the original port).
+// The TLS server name and the Host header are kept unchanged.
+func WithHostOverride(hosts map[string]string) HTTPOption {
+	return func(opts *httpOptions) {
+		if opts.hosts == nil {
+			opts.hosts = make(map[string]string)
+		}
+		for host, addr := range hosts {
+			opts.hosts[host] = addr
+		}
+	}
+}
+
+// NewHTTPClient creates an http.Client with the given options.
+//
+// TLS and host override options require the transport to be
+// *http.Transport (the default), or an error is returned. The
+// transport is cloned before modification.
+func NewHTTPClient(options ...HTTPOption) (*http.Client, error) {
+	opts := &httpOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	client := &http.Client{}
+	if opts.client != nil {
+		*client = *opts.client
+	}
+	if opts.transport != nil {
+		client.Transport = opts.transport
+	}
+	if opts.timeout > 0 {
+		client.Timeout = opts.timeout
+	}
+	if opts.checkRedirect != nil {
+		client.CheckRedirect = opts.checkRedirect
+	}
+
+	if len(opts.certificates) == 0 && opts.rootCAs == nil && len(opts.hosts) == 0 {
+		return client, nil
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	baseTransport, ok := base.(*http.Transport)
+	if !ok {
+		ctxErr := NewContextError("TLS and host override options require *http.Transport, got %T", base)
+		ctxErr.Prepend("ref", "client.transport")
+		return nil, ctxErr
+	}
+	transport := baseTransport.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if len(opts.certificates) > 0 {
+		transport.TLSClientConfig.Certificates = append(
+			transport.TLSClientConfig.Certificates, opts.certificates...)
+	}
+	if opts.rootCAs != nil {
+		transport.TLSClientConfig.RootCAs = opts.rootCAs
+	}
+	if len(opts.hosts) > 0 {
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dial(ctx, network, overrideHost(opts.hosts, addr))
+		}
+	}
+	client.Transport = transport
+	return client, nil
+}
+
+// overrideHost maps the dial address with the host override map
+func overrideHost(hosts map[string]string, addr string) string {
+	if mapped, ok := hosts[addr]; ok {
+		return mapped
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	mapped, ok := hosts[host]
+	if !ok {
+		return addr
+	}
+	if _, _, err := net.SplitHostPort(mapped); err == nil {
+		return mapped
+	}
+	return net.JoinHostPort(mapped, port)
+}
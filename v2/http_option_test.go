@@ -0,0 +1,118 @@
+package restit_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestNewHTTPService_TLS(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "host=%s certs=%d", r.Host, len(r.TLS.PeerCertificates))
+	})
+	testServer := httptest.NewUnstartedServer(handler)
+	testServer.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(testServer.Certificate())
+
+	// httptest certificate is valid for example.com
+	service := restit.NewHTTPService("https://example.com/api",
+		restit.WithRootCAs(pool),
+		restit.WithClientCertificates(testServer.TLS.Certificates[0]),
+		restit.WithHostOverride(map[string]string{
+			"example.com": testServer.Listener.Addr().String(),
+		}))
+
+	resp, err := service.Retrieve("/post/1").
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "host=example.com certs=1", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// without the root CAs, the certificate is not trusted
+	service = restit.NewHTTPService("https://example.com/api",
+		restit.WithClientCertificates(testServer.TLS.Certificates[0]),
+		restit.WithHostOverride(map[string]string{
+			"example.com": testServer.Listener.Addr().String(),
+		}))
+	if _, err = service.Retrieve("/post/1").Do(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestNewHTTPService_Client(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/old" {
+			http.Redirect(w, r, "/api/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte(r.URL.Path))
+	})
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	// follows redirect by default
+	service := restit.NewHTTPService(testServer.URL+"/api",
+		restit.WithHTTPClient(testServer.Client()))
+	resp, err := service.Retrieve("/old").
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "/api/new", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// redirect policy
+	service = restit.NewHTTPService(testServer.URL+"/api",
+		restit.WithTransport(testServer.Client().Transport),
+		restit.WithRedirectPolicy(restit.NoRedirect))
+	resp, err = service.Retrieve("/old").
+		Expect(restit.StatusCodeIs(http.StatusMovedPermanently)).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "/api/new", resp.Header().Get("Location"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+type dummyTransport struct{}
+
+func (dummyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("dummy transport")
+}
+
+func TestNewHTTPService_TransportError(t *testing.T) {
+	if _, err := restit.NewHTTPClient(
+		restit.WithTransport(dummyTransport{}),
+		restit.WithRootCAs(x509.NewCertPool()),
+	); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	service := restit.NewHTTPService("http://localhost/api",
+		restit.WithTransport(dummyTransport{}),
+		restit.WithHostOverride(map[string]string{"localhost": "127.0.0.1"}))
+	_, err := service.Retrieve("/post/1").Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if want, have := `ref="client.transport" message="TLS and host override options require *http.Transport, got restit_test.dummyTransport" curl="curl http://localhost/api/post/1"`, err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
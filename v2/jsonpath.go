@@ -0,0 +1,391 @@
+package restit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-restit/lzjson"
+)
+
+// segmentKind is the kind of path segment
+type segmentKind int
+
+const (
+	segmentKey segmentKind = iota
+	segmentIndex
+	segmentWildcard
+	segmentFilter
+)
+
+// pathSegment is a segment of a JSON path
+type pathSegment struct {
+	kind   segmentKind
+	key    string
+	index  int
+	filter *pathFilter
+}
+
+// pathFilter is a filter expression (e.g. `?(@.status=="active")`)
+type pathFilter struct {
+	path    []pathSegment // relative to "@"
+	op      string        // empty for existence test
+	literal []byte        // JSON literal
+}
+
+// jsonPath is a parsed JSON path
+type jsonPath struct {
+	raw      string
+	segments []pathSegment
+}
+
+// definite tells if the path matches at most one node
+func (p jsonPath) definite() bool {
+	for _, seg := range p.segments {
+		if seg.kind == segmentWildcard || seg.kind == segmentFilter {
+			return false
+		}
+	}
+	return true
+}
+
+// pathError is the error of resolving a JSON path
+type pathError struct {
+	path     string
+	resolved string // the part of path resolved (e.g. ".data.items[0]")
+	reason   string
+	missing  bool // the key or index does not exist
+}
+
+// Error implements error
+func (err *pathError) Error() string {
+	return fmt.Sprintf("unable to resolve path %#v (%s)", err.path, err.reason)
+}
+
+// ContextError returns the error as ContextError with the
+// resolved part of the path as ref
+func (err *pathError) ContextError() ContextError {
+	ctxErr := NewContextError("%s", err.Error())
+	ctxErr.Prepend("ref", "response"+err.resolved)
+	return ctxErr
+}
+
+// indexUnquoted returns the index of the first sep in s outside
+// of quotes, or -1
+func indexUnquoted(s string, sep string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case strings.HasPrefix(s[i:], sep):
+			return i
+		}
+	}
+	return -1
+}
+
+// unquote returns the string of a single or double quoted literal
+func unquote(s string) (string, bool) {
+	if len(s) < 2 || s[0] != s[len(s)-1] || (s[0] != '\'' && s[0] != '"') {
+		return "", false
+	}
+	if s[0] == '"' {
+		str, err := strconv.Unquote(s)
+		return str, err == nil
+	}
+	return strings.Replace(s[1:len(s)-1], `\'`, `'`, -1), true
+}
+
+// parseJSONPath parses a JSON path. The syntax is a subset of
+// JSONPath with optional leading "$":
+//
+//	data.items[0].id       keys and indexes
+//	data.items.0.id        numeric key as index
+//	data["odd.key"]        quoted key
+//	items[*].id, items.*   wildcard
+//	items[-1]              index from the end
+//	items[?(@.status=="active")]  filter (==, !=, <, <=, >, >=)
+//	items[?(@.tags)]       filter by existence
+func parseJSONPath(raw string) (path jsonPath, err error) {
+	path.raw = raw
+	s := strings.TrimPrefix(raw, "$")
+	fail := func(format string, v ...interface{}) (jsonPath, error) {
+		return jsonPath{}, fmt.Errorf("invalid path %#v (%s)", raw, fmt.Sprintf(format, v...))
+	}
+
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '[':
+			end := indexUnquoted(s[i:], "]")
+			if strings.HasPrefix(s[i:], "[?(") {
+				end = indexUnquoted(s[i:], ")]")
+				if end >= 0 {
+					end++
+				}
+			}
+			if end < 0 {
+				return fail("unterminated bracket at offset %d", i)
+			}
+			content := strings.TrimSpace(s[i+1 : i+end])
+			i += end + 1
+
+			switch {
+			case content == "*":
+				path.segments = append(path.segments, pathSegment{kind: segmentWildcard})
+			case strings.HasPrefix(content, "?(") && strings.HasSuffix(content, ")"):
+				filter, err := parseFilter(content[2 : len(content)-1])
+				if err != nil {
+					return fail("%s", err)
+				}
+				path.segments = append(path.segments, pathSegment{kind: segmentFilter, filter: filter})
+			default:
+				if key, ok := unquote(content); ok {
+					path.segments = append(path.segments, pathSegment{kind: segmentKey, key: key})
+				} else if n, err := strconv.Atoi(content); err == nil {
+					path.segments = append(path.segments, pathSegment{kind: segmentIndex, index: n})
+				} else {
+					return fail("invalid bracket %#v", content)
+				}
+			}
+		case '.':
+			if i == 0 && raw[0] != '$' {
+				return fail("unexpected \".\" at offset 0")
+			}
+			i++
+			if i < len(s) && s[i] == '.' {
+				return fail("recursive descent is not supported")
+			}
+			fallthrough
+		default:
+			end := strings.IndexAny(s[i:], ".[")
+			if end < 0 {
+				end = len(s) - i
+			}
+			name := s[i : i+end]
+			i += end
+			switch name {
+			case "":
+				return fail("empty key at offset %d", i)
+			case "*":
+				path.segments = append(path.segments, pathSegment{kind: segmentWildcard})
+			default:
+				path.segments = append(path.segments, pathSegment{kind: segmentKey, key: name})
+			}
+		}
+	}
+	return
+}
+
+// parseFilter parses the filter expression inside "?(...)"
+func parseFilter(expr string) (filter *pathFilter, err error) {
+	filter = &pathFilter{}
+	left := strings.TrimSpace(expr)
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if i := indexUnquoted(expr, op); i >= 0 {
+			filter.op = op
+			left = strings.TrimSpace(expr[:i])
+			right := strings.TrimSpace(expr[i+len(op):])
+			if str, ok := unquote(right); ok {
+				filter.literal, _ = json.Marshal(str)
+			} else if json.Valid([]byte(right)) {
+				filter.literal = []byte(right)
+			} else {
+				return nil, fmt.Errorf("invalid literal %#v in filter", right)
+			}
+			break
+		}
+	}
+	if !strings.HasPrefix(left, "@") {
+		return nil, fmt.Errorf("filter %#v must start with \"@\"", expr)
+	}
+	sub, err := parseJSONPath("$" + left[1:])
+	if err != nil {
+		return nil, err
+	}
+	if !sub.definite() {
+		return nil, fmt.Errorf("filter %#v must have a definite path", expr)
+	}
+	filter.path = sub.segments
+	return
+}
+
+// match tests if the node passes the filter
+func (f *pathFilter) match(node lzjson.Node) bool {
+	matches, err := evalSegments(node, f.path, "")
+	if err != nil || len(matches) == 0 {
+		return false
+	}
+	target := matches[0].node
+	raw := bytes.TrimSpace(target.Raw())
+	switch f.op {
+	case "":
+		return true
+	case "==":
+		return jsonEqual(raw, f.literal)
+	case "!=":
+		return !jsonEqual(raw, f.literal)
+	}
+
+	// ordering
+	literal := lzjson.Decode(bytes.NewReader(f.literal))
+	var cmp int
+	switch {
+	case target.Type() == lzjson.TypeNumber && literal.Type() == lzjson.TypeNumber:
+		a, b := target.Number(), literal.Number()
+		if a < b {
+			cmp = -1
+		} else if a > b {
+			cmp = 1
+		}
+	case target.Type() == lzjson.TypeString && literal.Type() == lzjson.TypeString:
+		cmp = strings.Compare(target.String(), literal.String())
+	default:
+		return false
+	}
+	switch f.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// pathMatch is a node matched by JSON path
+type pathMatch struct {
+	node lzjson.Node
+	path string // normalized path of the node (e.g. ".items[0].id")
+}
+
+// defined tells if the node is defined
+func defined(node lzjson.Node) bool {
+	t := node.Type()
+	return t != lzjson.TypeUndefined && t != lzjson.TypeError
+}
+
+// evalSegments evaluates the segments from root. Returns *pathError
+// if a definite segment cannot be resolved.
+func evalSegments(root lzjson.Node, segments []pathSegment, raw string) (matches []pathMatch, err error) {
+	matches = []pathMatch{{root, ""}}
+	definite := true
+	for _, seg := range segments {
+		var next []pathMatch
+		var reason string
+		var missing bool
+		for _, m := range matches {
+			switch seg.kind {
+			case segmentKey:
+				switch m.node.Type() {
+				case lzjson.TypeObject:
+					if child := m.node.Get(seg.key); defined(child) {
+						next = append(next, pathMatch{child, m.path + "." + seg.key})
+					} else {
+						reason, missing = fmt.Sprintf("key %#v not found", seg.key), true
+					}
+				case lzjson.TypeArray:
+					n, convErr := strconv.Atoi(seg.key)
+					if convErr != nil {
+						reason = fmt.Sprintf("expected object for key %#v, got array", seg.key)
+					} else if n < 0 || n >= m.node.Len() {
+						reason, missing = fmt.Sprintf("index %d out of range (length %d)", n, m.node.Len()), true
+					} else {
+						next = append(next, pathMatch{m.node.GetN(n), fmt.Sprintf("%s[%d]", m.path, n)})
+					}
+				default:
+					reason = fmt.Sprintf("expected object for key %#v, got %s", seg.key, jsonType(m.node))
+				}
+			case segmentIndex:
+				if m.node.Type() != lzjson.TypeArray {
+					reason = fmt.Sprintf("expected array for index %d, got %s", seg.index, jsonType(m.node))
+					continue
+				}
+				n := seg.index
+				if n < 0 {
+					n += m.node.Len()
+				}
+				if n < 0 || n >= m.node.Len() {
+					reason, missing = fmt.Sprintf("index %d out of range (length %d)", seg.index, m.node.Len()), true
+					continue
+				}
+				next = append(next, pathMatch{m.node.GetN(n), fmt.Sprintf("%s[%d]", m.path, n)})
+			case segmentWildcard, segmentFilter:
+				for _, child := range children(m) {
+					if seg.kind == segmentWildcard || seg.filter.match(child.node) {
+						next = append(next, child)
+					}
+				}
+			}
+		}
+		if len(next) == 0 && definite && reason != "" {
+			return nil, &pathError{path: raw, resolved: matches[0].path, reason: reason, missing: missing}
+		}
+		if seg.kind == segmentWildcard || seg.kind == segmentFilter {
+			definite = false
+		}
+		matches = next
+	}
+	return
+}
+
+// children returns the items of array, or the values of object
+// in the order of keys
+func children(m pathMatch) (matches []pathMatch) {
+	switch m.node.Type() {
+	case lzjson.TypeArray:
+		for i := 0; i < m.node.Len(); i++ {
+			matches = append(matches, pathMatch{m.node.GetN(i), fmt.Sprintf("%s[%d]", m.path, i)})
+		}
+	case lzjson.TypeObject:
+		keys := m.node.GetKeys()
+		sort.Strings(keys)
+		for _, key := range keys {
+			matches = append(matches, pathMatch{m.node.Get(key), m.path + "." + key})
+		}
+	}
+	return
+}
+
+// evalPath evaluates the JSON path on root. Definite paths (without
+// wildcard or filter) return exactly one match or an error. Other
+// paths return all the matches, which may be empty.
+func evalPath(root lzjson.Node, raw string) (matches []pathMatch, definite bool, err ContextError) {
+	path, parseErr := parseJSONPath(raw)
+	if parseErr != nil {
+		ctxErr := NewContextError("%s", parseErr)
+		ctxErr.Prepend("ref", "response")
+		return nil, false, ctxErr
+	}
+	matches, evalErr := evalSegments(root, path.segments, raw)
+	if evalErr != nil {
+		return nil, false, evalErr.(*pathError).ContextError()
+	}
+	return matches, path.definite(), nil
+}
+
+// lookupPath returns the first node matched by the JSON path
+func lookupPath(root lzjson.Node, raw string) (node lzjson.Node, err ContextError) {
+	matches, _, err := evalPath(root, raw)
+	if err != nil {
+		return
+	}
+	if len(matches) == 0 {
+		err = NewContextError("unable to resolve path %#v (no match)", raw)
+		err.Prepend("ref", "response")
+		return
+	}
+	return matches[0].node, nil
+}
@@ -0,0 +1,140 @@
+package restit_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-restit/lzjson"
+	restit "github.com/go-restit/restit/v2"
+)
+
+const dummyNestedJSON = `{
+	"data": {
+		"items": [
+			{"id": "1", "status": "active", "score": 10, "tags": ["a"]},
+			{"id": "2", "status": "deleted", "score": 20},
+			{"id": "3", "status": "active", "score": 30, "tags": []}
+		]
+	},
+	"meta": {"pagination": {"total": 3, "pages": [1]}},
+	"odd.key": [1, 2]
+}`
+
+func TestLengthIs_Path(t *testing.T) {
+	ctx := context.Background()
+	resp := jsonResponse(dummyNestedJSON)
+
+	tests := []struct {
+		path string
+		n    int
+	}{
+		{"data.items", 3},
+		{"$.data.items", 3},
+		{"meta.pagination.pages", 1},
+		{`["odd.key"]`, 2},
+		{"data.items[0].tags", 1},
+		{"data.items.0.tags", 1},
+		{"data.items[-1].tags", 0},
+		{"data.items[*]", 3},
+		{"data.items[*].tags", 2},
+		{"meta.*", 1},
+		{`data.items[?(@.status=="active")]`, 2},
+		{`data.items[?(@.status!='active')]`, 1},
+		{`data.items[?(@.score >= 20)]`, 2},
+		{`data.items[?(@.tags)]`, 2},
+		{`data.items[?(@.status=="unknown")]`, 0},
+	}
+	for _, test := range tests {
+		if err := restit.LengthIs(test.path, test.n).Do(ctx, resp); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.path, err)
+		}
+	}
+}
+
+func TestLengthIs_BadPath(t *testing.T) {
+	ctx := context.Background()
+	resp := jsonResponse(dummyNestedJSON)
+
+	tests := []struct {
+		path string
+		log  string
+	}{
+		{
+			path: "data.items[5].tags",
+			log:  `ref="response.data.items" message="unable to resolve path \"data.items[5].tags\" (index 5 out of range (length 3))"`,
+		},
+		{
+			path: "meta.pagination.size",
+			log:  `ref="response.meta.pagination" message="unable to resolve path \"meta.pagination.size\" (key \"size\" not found)"`,
+		},
+		{
+			path: "data.items[0].id.value",
+			log:  `ref="response.data.items[0].id" message="unable to resolve path \"data.items[0].id.value\" (expected object for key \"value\", got string)"`,
+		},
+		{
+			path: "data.items[?(@.status=",
+			log:  `ref="response" message="invalid path \"data.items[?(@.status=\" (unterminated bracket at offset 10)"`,
+		},
+		{
+			path: "meta.pagination.total",
+			log:  `ref="response.meta.pagination.total" message="expected \"meta.pagination.total\" to be type TypeArray, got TypeNumber" response=` + fmt.Sprintf("%#v", dummyNestedJSON),
+		},
+	}
+	for _, test := range tests {
+		err := restit.LengthIs(test.path, 1).Do(ctx, resp)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", test.path)
+		} else if want, have := test.log, err.(restit.ContextError).Log(); want != have {
+			t.Errorf("%s\nexpected: %s\ngot:      %s", test.path, want, have)
+		}
+	}
+}
+
+func TestNthTest_Path(t *testing.T) {
+	ctx := context.Background()
+	resp := jsonResponse(dummyNestedJSON)
+
+	idIs := func(id string) restit.JSONTest {
+		return restit.DescribeJSON("id is "+id, func(node lzjson.Node) (err error) {
+			if want, have := id, node.Get("id").String(); want != have {
+				err = fmt.Errorf("expected %#v, got %#v", want, have)
+			}
+			return
+		})
+	}
+	if err := restit.Nth(2).Of("data.items").Is(idIs("3")).Do(ctx, resp); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := restit.Nth(1).Of(`data.items[?(@.status=="active")]`).Is(idIs("3")).Do(ctx, resp); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := restit.Nth(2).Of(`data.items[?(@.status=="active")]`).Is(idIs("3")).Do(ctx, resp); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestCapture_Path(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(dummyNestedJSON))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	if _, err := service.Retrieve("/items").
+		Capture("itemID", `data.items[?(@.status=="deleted")].id`).
+		Do(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "2", mustGet(t, service.Vars, "itemID"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func mustGet(t *testing.T, vars *restit.Vars, name string) string {
+	value, ok := vars.Get(name)
+	if !ok {
+		t.Fatalf("variable %#v is not set", name)
+	}
+	return value
+}
@@ -0,0 +1,239 @@
+package restit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// LoadOptions configures Load
+type LoadOptions struct {
+
+	// Concurrency is the number of workers running the case.
+	// Defaults to 1.
+	Concurrency int
+
+	// Requests is the total number of requests to send.
+	// If zero, requests are sent until Duration expires.
+	Requests int
+
+	// Duration is the maximum duration of the test.
+	// If zero, the test ends after sending all Requests.
+	Duration time.Duration
+
+	// RatePerSecond limits the number of requests started
+	// per second among all workers. Zero means no limit.
+	RatePerSecond float64
+}
+
+// LoadReport is the result of Load
+type LoadReport struct {
+	Requests    int
+	Errors      int
+	Duration    time.Duration
+	Throughput  float64 // requests per second
+	StatusCodes map[int]int
+	ErrorCounts map[string]int
+	Latencies   []time.Duration // sorted
+	P50         time.Duration
+	P90         time.Duration
+	P99         time.Duration
+}
+
+// HistogramBucket counts the latencies between From (inclusive)
+// and To (exclusive)
+type HistogramBucket struct {
+	From  time.Duration
+	To    time.Duration
+	Count int
+}
+
+// Percentile returns the latency of the given percentile (0 - 100)
+func (r *LoadReport) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.Latencies))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(r.Latencies) {
+		i = len(r.Latencies) - 1
+	}
+	return r.Latencies[i]
+}
+
+// Histogram returns the latency histogram of n equal width buckets
+func (r *LoadReport) Histogram(n int) (buckets []HistogramBucket) {
+	if len(r.Latencies) == 0 || n <= 0 {
+		return
+	}
+	min, max := r.Latencies[0], r.Latencies[len(r.Latencies)-1]
+	width := (max-min)/time.Duration(n) + 1
+	buckets = make([]HistogramBucket, n)
+	for i := range buckets {
+		buckets[i].From = min + width*time.Duration(i)
+		buckets[i].To = buckets[i].From + width
+	}
+	for _, latency := range r.Latencies {
+		buckets[int((latency-min)/width)].Count++
+	}
+	return
+}
+
+// String returns a human readable summary of the report
+func (r *LoadReport) String() string {
+	lines := []string{
+		fmt.Sprintf("requests: %d, errors: %d, duration: %s, throughput: %.2f req/s",
+			r.Requests, r.Errors, r.Duration, r.Throughput),
+		fmt.Sprintf("latency: p50=%s p90=%s p99=%s", r.P50, r.P90, r.P99),
+	}
+	codes := make([]int, 0, len(r.StatusCodes))
+	for code := range r.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		lines = append(lines, fmt.Sprintf("status %d: %d", code, r.StatusCodes[code]))
+	}
+	errs := make([]string, 0, len(r.ErrorCounts))
+	for err := range r.ErrorCounts {
+		errs = append(errs, err)
+	}
+	sort.Strings(errs)
+	for _, err := range errs {
+		lines = append(lines, fmt.Sprintf("error %s: %d", err, r.ErrorCounts[err]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// loadErrorKey groups errors by the failed expectation
+func loadErrorKey(err error) string {
+	if cErr, ok := err.(ContextError); ok && cErr.Get("expectation") != nil {
+		return fmt.Sprintf("expectation #%v (%v)", cErr.Get("expectation"), cErr.Get("desc"))
+	}
+	return err.Error()
+}
+
+// Load runs clones of the case repeatedly and concurrently with
+// its CaseHandler, then reports the throughput, errors, status
+// codes and latencies
+func Load(c *Case, opts LoadOptions) *LoadReport {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Requests <= 0 && opts.Duration <= 0 {
+		opts.Requests = 1
+	}
+
+	report := &LoadReport{
+		StatusCodes: make(map[int]int),
+		ErrorCounts: make(map[string]int),
+	}
+
+	// make the request body replayable before the clones
+	// share it concurrently
+	if c.Request != nil {
+		if err := c.bufferBody(); err != nil {
+			report.Errors++
+			report.ErrorCounts[loadErrorKey(err)]++
+			return report
+		}
+	}
+	var mutex sync.Mutex
+
+	// produce jobs with rate limit and deadline
+	jobs := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		var deadline <-chan time.Time
+		if opts.Duration > 0 {
+			timer := time.NewTimer(opts.Duration)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		// rates above 1e9 (i.e. interval below 1ns) are unlimited
+		var tick <-chan time.Time
+		if interval := time.Duration(float64(time.Second) / opts.RatePerSecond); opts.RatePerSecond > 0 && interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for i := 0; opts.Requests <= 0 || i < opts.Requests; i++ {
+			if tick != nil && i > 0 {
+				select {
+				case <-tick:
+				case <-deadline:
+					return
+				}
+			}
+			select {
+			case jobs <- struct{}{}:
+			case <-deadline:
+				return
+			}
+		}
+	}()
+
+	// run the jobs
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				clone := c.Clone()
+				reqStart := time.Now()
+				resp, err := clone.Do()
+				latency := time.Since(reqStart)
+
+				mutex.Lock()
+				report.Requests++
+				report.Latencies = append(report.Latencies, latency)
+				if resp != nil {
+					report.StatusCodes[resp.StatusCode()]++
+				}
+				if err != nil {
+					report.Errors++
+					report.ErrorCounts[loadErrorKey(err)]++
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report.Duration = time.Since(start)
+	if report.Duration > 0 {
+		report.Throughput = float64(report.Requests) / report.Duration.Seconds()
+	}
+	sort.Slice(report.Latencies, func(i, j int) bool {
+		return report.Latencies[i] < report.Latencies[j]
+	})
+	report.P50 = report.Percentile(50)
+	report.P90 = report.Percentile(90)
+	report.P99 = report.Percentile(99)
+	return report
+}
+
+// BenchmarkLoad runs the case b.N times with Load and reports the
+// latency percentiles as benchmark metrics. Errors fail the benchmark.
+// Requests and Duration in opts are ignored.
+func BenchmarkLoad(b *testing.B, c *Case, opts LoadOptions) *LoadReport {
+	b.Helper()
+	opts.Requests, opts.Duration = b.N, 0
+	b.ResetTimer()
+	report := Load(c, opts)
+	b.StopTimer()
+
+	b.ReportMetric(float64(report.P50.Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(report.P90.Nanoseconds()), "p90-ns")
+	b.ReportMetric(float64(report.P99.Nanoseconds()), "p99-ns")
+	if report.Errors > 0 {
+		b.Errorf("%d of %d requests failed\n%s", report.Errors, report.Requests, report)
+	}
+	return report
+}
@@ -0,0 +1,157 @@
+package restit_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestLoad(t *testing.T) {
+	var count int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// every 4th request fails
+		if atomic.AddInt32(&count, 1)%4 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	report := restit.Load(service.Retrieve("/post/1").
+		Expect(restit.StatusCodeIs(http.StatusOK)), restit.LoadOptions{
+		Concurrency: 4,
+		Requests:    40,
+	})
+	if want, have := 40, report.Requests; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := 10, report.Errors; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := 30, report.StatusCodes[http.StatusOK]; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := 10, report.StatusCodes[http.StatusInternalServerError]; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := 10, report.ErrorCounts["expectation #0 (status code is 200)"]; want != have {
+		t.Errorf("expected %#v, got %#v\n%s", want, have, report)
+	}
+	if want, have := 40, len(report.Latencies); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if report.P50 > report.P90 || report.P90 > report.P99 {
+		t.Errorf("unexpected percentiles: %s %s %s", report.P50, report.P90, report.P99)
+	}
+	if report.Throughput <= 0 {
+		t.Errorf("unexpected throughput: %f", report.Throughput)
+	}
+
+	total := 0
+	for _, bucket := range report.Histogram(5) {
+		total += bucket.Count
+	}
+	if want, have := 40, total; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if !strings.Contains(report.String(), "status 500: 10") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+}
+
+func TestLoad_DurationAndRate(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	report := restit.Load(service.Retrieve("/post/1"), restit.LoadOptions{
+		Concurrency:   2,
+		Duration:      100 * time.Millisecond,
+		RatePerSecond: 100,
+	})
+	if report.Requests < 5 || report.Requests > 12 {
+		t.Errorf("unexpected number of requests: %d", report.Requests)
+	}
+	if want, have := 0, report.Errors; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestLoad_Unlimited(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	report := restit.Load(service.Retrieve("/post/1"), restit.LoadOptions{
+		Requests:      10,
+		RatePerSecond: 2e9,
+	})
+	if want, have := 10, report.Requests; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestLoad_ConnectionReuse(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+	service := restit.NewHTTPService(testServer.URL + "/api")
+
+	before := runtime.NumGoroutine()
+	report := restit.Load(service.Retrieve("/post/1").
+		Expect(restit.StatusCodeIs(http.StatusOK)), restit.LoadOptions{
+		Concurrency: 1,
+		Requests:    200,
+	})
+	if want, have := 0, report.Errors; want != have {
+		t.Errorf("expected %#v, got %#v\n%s", want, have, report)
+	}
+
+	// a reused connection keeps a few goroutines, one per
+	// request would leave hundreds
+	if have := runtime.NumGoroutine(); have > before+10 {
+		t.Errorf("expected about %d goroutines, got %d", before, have)
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	restit.BenchmarkLoad(b, service.Retrieve("/post/1").
+		Expect(restit.StatusCodeIs(http.StatusOK)), restit.LoadOptions{
+		Concurrency: 4,
+	})
+}
+
+func TestLoad_BodyError(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	testCase := service.Create(nil, "/posts")
+	testCase.Request.Body = ioutil.NopCloser(iotest.ErrReader(errors.New("dummy read error")))
+	report := restit.Load(testCase, restit.LoadOptions{Requests: 10})
+	if want, have := 0, report.Requests; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := 1, report.Errors; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := 1, report.ErrorCounts["dummy read error"]; want != have {
+		t.Errorf("expected %#v, got %#v\n%s", want, have, report)
+	}
+}
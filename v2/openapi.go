@@ -0,0 +1,496 @@
+package restit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-restit/lzjson"
+)
+
+// openAPIExample is an OpenAPI example object
+type openAPIExample struct {
+	Ref   string          `json:"$ref"`
+	Value json.RawMessage `json:"value"`
+}
+
+// openAPIParameter is an OpenAPI parameter object
+type openAPIParameter struct {
+	Ref      string                    `json:"$ref"`
+	Name     string                    `json:"name"`
+	In       string                    `json:"in"`
+	Required bool                      `json:"required"`
+	Schema   *Schema                   `json:"schema"`
+	Example  json.RawMessage           `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+}
+
+// openAPIMediaType is an OpenAPI media type object
+type openAPIMediaType struct {
+	Schema   *Schema                   `json:"schema"`
+	Example  json.RawMessage           `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+}
+
+// openAPIRequestBody is an OpenAPI request body object
+type openAPIRequestBody struct {
+	Ref     string                      `json:"$ref"`
+	Content map[string]openAPIMediaType `json:"content"`
+}
+
+// openAPIHeader is an OpenAPI header object
+type openAPIHeader struct {
+	Ref      string  `json:"$ref"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// openAPIResponse is an OpenAPI response object
+type openAPIResponse struct {
+	Ref     string                      `json:"$ref"`
+	Headers map[string]openAPIHeader    `json:"headers"`
+	Content map[string]openAPIMediaType `json:"content"`
+}
+
+// openAPIOperation is an OpenAPI operation object
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Parameters  []openAPIParameter         `json:"parameters"`
+	RequestBody *openAPIRequestBody        `json:"requestBody"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+// operation is an operation of OpenAPI with its method and path
+type operation struct {
+	method     string
+	path       string
+	re         *regexp.Regexp
+	pointer    string
+	parameters []openAPIParameter
+	*openAPIOperation
+}
+
+// name returns the operation ID, or method and path of the
+// operation if there is none
+func (op *operation) name() string {
+	if op.OperationID != "" {
+		return op.OperationID
+	}
+	return op.method + " " + op.path
+}
+
+// OpenAPI is an OpenAPI 3 document. It validates responses
+// against the documented operations, and generates smoke test
+// cases from the examples.
+type OpenAPI struct {
+	doc        *schemaDocument
+	basePath   string
+	operations []*operation
+}
+
+// methodOrder is the order of operations in a path
+var methodOrder = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// LoadOpenAPI loads the OpenAPI 3 document (JSON) from file
+func LoadOpenAPI(path string) (spec *OpenAPI, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	return ParseOpenAPI(data)
+}
+
+// ParseOpenAPI parses the OpenAPI 3 document (JSON)
+func ParseOpenAPI(data []byte) (spec *OpenAPI, err error) {
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document (%s)", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %#v", doc.OpenAPI)
+	}
+
+	spec = &OpenAPI{}
+	if spec.doc, err = newSchemaDocument(data); err != nil {
+		return nil, err
+	}
+	if len(doc.Servers) > 0 {
+		if u, err := url.Parse(doc.Servers[0].URL); err == nil {
+			spec.basePath = strings.TrimRight(u.EscapedPath(), "/")
+		}
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := doc.Paths[path]
+		var common []openAPIParameter
+		if raw, ok := item["parameters"]; ok {
+			if err = json.Unmarshal(raw, &common); err != nil {
+				return nil, fmt.Errorf("invalid parameters of path %#v (%s)", path, err)
+			}
+		}
+
+		// path template to regular expression
+		pattern := "^"
+		last := 0
+		for _, loc := range rePathParam.FindAllStringIndex(path, -1) {
+			pattern += regexp.QuoteMeta(path[last:loc[0]]) + "[^/]+"
+			last = loc[1]
+		}
+		pattern += regexp.QuoteMeta(path[last:]) + "/?$"
+
+		for _, method := range methodOrder {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			op := &operation{
+				method:           strings.ToUpper(method),
+				path:             path,
+				re:               regexp.MustCompile(pattern),
+				pointer:          "#/paths/" + pointerEscape(path) + "/" + method,
+				openAPIOperation: &openAPIOperation{},
+			}
+			if err = json.Unmarshal(raw, op.openAPIOperation); err != nil {
+				return nil, fmt.Errorf("invalid operation %s (%s)", op.name(), err)
+			}
+			if op.parameters, err = spec.parameters(common, op.Parameters); err != nil {
+				return nil, fmt.Errorf("invalid operation %s (%s)", op.name(), err)
+			}
+			spec.operations = append(spec.operations, op)
+		}
+	}
+
+	// match the paths with less parameters first
+	sort.SliceStable(spec.operations, func(i, j int) bool {
+		return strings.Count(spec.operations[i].path, "{") < strings.Count(spec.operations[j].path, "{")
+	})
+	return
+}
+
+// parameters resolves and merges the path and operation parameters
+func (spec *OpenAPI) parameters(lists ...[]openAPIParameter) (params []openAPIParameter, err error) {
+	index := make(map[string]int)
+	for _, list := range lists {
+		for _, param := range list {
+			if param.Ref != "" {
+				if err = spec.doc.resolve(param.Ref, &param); err != nil {
+					return
+				}
+			}
+			key := param.In + ":" + param.Name
+			if i, ok := index[key]; ok {
+				params[i] = param
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, param)
+		}
+	}
+	return
+}
+
+// match finds the operation of the request method and escaped path
+func (spec *OpenAPI) match(method, path string) *operation {
+	if spec.basePath != "" && (path == spec.basePath || strings.HasPrefix(path, spec.basePath+"/")) {
+		path = strings.TrimPrefix(path, spec.basePath)
+	}
+	for _, op := range spec.operations {
+		if op.method == method && op.re.MatchString(path) {
+			return op
+		}
+	}
+	return nil
+}
+
+// response finds the documented response of the status code
+func (spec *OpenAPI) response(op *operation, status int) (resp *openAPIResponse, pointer string, err error) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if r, ok := op.Responses[key]; ok {
+			pointer = op.pointer + "/responses/" + key
+			if r.Ref != "" {
+				pointer = r.Ref
+				if err = spec.doc.resolve(r.Ref, &r); err != nil {
+					return
+				}
+			}
+			return &r, pointer, nil
+		}
+	}
+	return
+}
+
+// mediaType finds the documented media type of the content type
+func mediaType(content map[string]openAPIMediaType, contentType string) (key string, media openAPIMediaType, ok bool) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = contentType
+	}
+	candidates := []string{mt, "*/*"}
+	if i := strings.Index(mt, "/"); i >= 0 {
+		candidates = []string{mt, mt[:i] + "/*", "*/*"}
+	}
+	for _, key = range candidates {
+		if media, ok = content[key]; ok {
+			return
+		}
+	}
+	return
+}
+
+// headerNode converts the header value into JSON node
+// for validation against the header schema
+func headerNode(schema *Schema, value string) lzjson.Node {
+	raw, _ := json.Marshal(value)
+	if schema != nil {
+		for _, t := range schema.Type {
+			if t == "integer" || t == "number" || t == "boolean" {
+				raw = []byte(value)
+			}
+		}
+	}
+	return lzjson.Decode(strings.NewReader(string(raw)))
+}
+
+// Validate returns an Expectation which validates the status,
+// headers and body of the response against the operation matched
+// by the request method and path. All failures are reported with
+// the JSON pointers in the body ("pointer") and in the OpenAPI
+// document ("schema").
+func (spec *OpenAPI) Validate() Expectation {
+	return Describe(
+		"response conforms to OpenAPI",
+		func(ctx context.Context, resp Response) (err error) {
+			req := RequestFromContext(ctx)
+			if req == nil {
+				ctxErr := NewContextError("request not found in context")
+				ctxErr.Prepend("ref", "openapi")
+				return ctxErr
+			}
+			op := spec.match(req.Method, req.URL.EscapedPath())
+			if op == nil {
+				ctxErr := NewContextError("no operation matches %s %s", req.Method, req.URL.Path)
+				ctxErr.Prepend("ref", "openapi")
+				return ctxErr
+			}
+
+			// status code
+			documented, pointer, err := spec.response(op, resp.StatusCode())
+			if err != nil {
+				ctxErr := NewContextError("%s", err)
+				ctxErr.Prepend("ref", "openapi")
+				ctxErr.Append("operation", op.name())
+				return ctxErr
+			} else if documented == nil {
+				ctxErr := NewContextError("status %d is not documented", resp.StatusCode())
+				ctxErr.Prepend("ref", "header status code")
+				ctxErr.Append("operation", op.name())
+				ctxErr.Append("schema", op.pointer+"/responses")
+				return ctxErr
+			}
+
+			var errs []ContextError
+			appendViolations := func(ref string, violations []schemaViolation) {
+				for _, violation := range violations {
+					ctxErr := violation.ContextError(ref)
+					ctxErr.Append("operation", op.name())
+					errs = append(errs, ctxErr)
+				}
+			}
+
+			// headers
+			names := make([]string, 0, len(documented.Headers))
+			for name := range documented.Headers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				header := documented.Headers[name]
+				headerPointer := pointer + "/headers/" + pointerEscape(name)
+				if header.Ref != "" {
+					headerPointer = header.Ref
+					if err := spec.doc.resolve(header.Ref, &header); err != nil {
+						appendViolations("header."+name, []schemaViolation{{"", headerPointer, err.Error()}})
+						continue
+					}
+				}
+				value := resp.Header().Get(name)
+				if value == "" {
+					if header.Required {
+						appendViolations("header."+name, []schemaViolation{{"", headerPointer + "/required",
+							fmt.Sprintf("required header %#v is missing", name)}})
+					}
+					continue
+				}
+				validator := &schemaValidator{doc: spec.doc}
+				validator.validate(header.Schema, headerNode(header.Schema, value), "", headerPointer+"/schema")
+				appendViolations("header."+name, validator.violations)
+			}
+
+			// body
+			if len(documented.Content) > 0 {
+				contentType := resp.Header().Get("Content-Type")
+				key, media, ok := mediaType(documented.Content, contentType)
+				if !ok {
+					appendViolations("header.Content-Type", []schemaViolation{{"", pointer + "/content",
+						fmt.Sprintf("content type %#v is not documented", contentType)}})
+				} else if media.Schema != nil && (strings.Contains(key, "json") || strings.Contains(contentType, "json")) {
+					root, err := resp.JSON()
+					if err != nil {
+						appendViolations("response", []schemaViolation{{"", pointer + "/content/" + pointerEscape(key),
+							fmt.Sprintf("response is not JSON (%s)", err)}})
+					} else {
+						validator := &schemaValidator{doc: spec.doc}
+						validator.validate(media.Schema, root, "",
+							pointer+"/content/"+pointerEscape(key)+"/schema")
+						appendViolations("response.body", validator.violations)
+					}
+				}
+			}
+
+			switch len(errs) {
+			case 0:
+				return nil
+			case 1:
+				return errs[0]
+			}
+			return &MultiError{Errors: errs}
+		})
+}
+
+// example returns the first example of the example, examples
+// or schema example / default / enum
+func (spec *OpenAPI) example(example json.RawMessage, examples map[string]openAPIExample, schema *Schema) json.RawMessage {
+	if len(example) > 0 {
+		return example
+	}
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ex := examples[name]
+		if ex.Ref != "" && spec.doc.resolve(ex.Ref, &ex) != nil {
+			continue
+		}
+		if len(ex.Value) > 0 {
+			return ex.Value
+		}
+	}
+	if schema != nil && schema.Ref != "" {
+		schema, _ = spec.doc.schema(schema.Ref)
+	}
+	if schema != nil {
+		switch {
+		case len(schema.Example) > 0:
+			return schema.Example
+		case len(schema.Default) > 0:
+			return schema.Default
+		case len(schema.Enum) > 0:
+			return schema.Enum[0]
+		}
+	}
+	return nil
+}
+
+// exampleString returns the example as parameter value
+func exampleString(example json.RawMessage) string {
+	var str string
+	if err := json.Unmarshal(example, &str); err == nil {
+		return str
+	}
+	return strings.TrimSpace(string(example))
+}
+
+// SmokeScenario returns a scenario with a case for each operation of
+// the service, built from the examples of the parameters and request
+// body. Each case expects a documented 2XX status (if any) and
+// validates the response with Validate. The scenario runs all steps
+// even if any fails.
+func (spec *OpenAPI) SmokeScenario(s *Service) *Scenario {
+	scenario := NewScenario("OpenAPI smoke test")
+	scenario.StopOnFailure = false
+
+	for _, op := range spec.operations {
+		c := s.NewCase(op.method, nil, op.path)
+		for _, param := range op.parameters {
+			example := spec.example(param.Example, param.Examples, param.Schema)
+			if example == nil {
+				continue
+			}
+			switch param.In {
+			case "path":
+				c.WithPathParam(param.Name, exampleString(example))
+			case "query":
+				c.AddQuery(param.Name, exampleString(example))
+			case "header":
+				c.AddHeader(param.Name, exampleString(example))
+			}
+		}
+
+		if body := op.RequestBody; body != nil {
+			if body.Ref != "" {
+				if err := spec.doc.resolve(body.Ref, body); err != nil {
+					c.fail(err)
+				}
+			}
+			contentTypes := make([]string, 0, len(body.Content))
+			for contentType := range body.Content {
+				contentTypes = append(contentTypes, contentType)
+			}
+			sort.Slice(contentTypes, func(i, j int) bool {
+				// prefer JSON
+				iJSON, jJSON := strings.Contains(contentTypes[i], "json"), strings.Contains(contentTypes[j], "json")
+				if iJSON != jJSON {
+					return iJSON
+				}
+				return contentTypes[i] < contentTypes[j]
+			})
+			for _, contentType := range contentTypes {
+				media := body.Content[contentType]
+				if example := spec.example(media.Example, media.Examples, media.Schema); example != nil {
+					if err := c.setBody(string(example), RawEncoder(contentType)); err != nil {
+						c.fail(err)
+					}
+					break
+				}
+			}
+		}
+
+		// expect the first documented success status
+		codes := make([]string, 0, len(op.Responses))
+		for code := range op.Responses {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			if n, err := strconv.Atoi(code); err == nil && n >= 200 && n < 300 {
+				c.Expect(StatusCodeIs(n))
+				break
+			}
+		}
+		c.Expect(spec.Validate())
+
+		scenario.Step(op.name(), c)
+	}
+	return scenario
+}
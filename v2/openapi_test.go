@@ -0,0 +1,210 @@
+package restit_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+const dummyOpenAPI = `{
+  "openapi": "3.0.3",
+  "info": {"title": "dummy", "version": "1.0"},
+  "servers": [{"url": "http://localhost/api"}],
+  "paths": {
+    "/posts": {
+      "post": {
+        "operationId": "createPost",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/Post"},
+              "example": {"id": "post-1", "title": "hello"}
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "created",
+            "headers": {
+              "Location": {"required": true, "schema": {"type": "string", "pattern": "^/api/posts/"}}
+            },
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Post"}}
+            }
+          }
+        }
+      }
+    },
+    "/posts/{id}": {
+      "parameters": [
+        {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}, "example": "post-1"}
+      ],
+      "get": {
+        "responses": {
+          "200": {
+            "description": "found",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Post"}}
+            }
+          },
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Post": {
+        "type": "object",
+        "required": ["id", "title"],
+        "properties": {
+          "id": {"type": "string"},
+          "title": {"type": "string", "minLength": 1},
+          "tags": {"type": "array", "items": {"type": "string"}}
+        }
+      }
+    },
+    "responses": {
+      "NotFound": {
+        "description": "not found",
+        "content": {
+          "application/json": {
+            "schema": {"type": "object", "required": ["error"], "properties": {"error": {"type": "string"}}}
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestOpenAPI_Validate(t *testing.T) {
+	spec, err := restit.ParseOpenAPI([]byte(dummyOpenAPI))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	posts := map[string]interface{}{
+		"post-1":  map[string]interface{}{"id": "post-1", "title": "hello"},
+		"invalid": map[string]interface{}{"id": 1, "title": "", "tags": []interface{}{"a", 2}},
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		id := strings.TrimPrefix(r.URL.Path, "/api/posts/")
+		switch {
+		case r.URL.Path == "/api/posts" && r.Method == "POST":
+			w.Header().Set("Location", "/api/posts/post-1")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(posts["post-1"])
+		case posts[id] != nil:
+			json.NewEncoder(w).Encode(posts[id])
+		case id == "teapot":
+			w.WriteHeader(http.StatusTeapot)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+		}
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	// valid responses
+	for _, id := range []string{"post-1", "missing"} {
+		if _, err := service.Retrieve("/posts", id).Expect(spec.Validate()).Do(); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	}
+
+	// every violation is reported with pointers
+	_, err = service.Retrieve("/posts/invalid").Expect(spec.Validate()).Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	multiErr, ok := err.(*restit.MultiError)
+	if !ok {
+		t.Fatalf("expected *restit.MultiError, got %#v", err)
+	}
+	want := [][3]string{
+		{"/id", "#/components/schemas/Post/properties/id/type", "expected type string, got integer"},
+		{"/tags/1", "#/components/schemas/Post/properties/tags/items/type", "expected type string, got integer"},
+		{"/title", "#/components/schemas/Post/properties/title/minLength", "expected length at least 1, got 0"},
+	}
+	if len(multiErr.Errors) != len(want) {
+		t.Fatalf("expected %d errors, got %s", len(want), multiErr.Log())
+	}
+	for i, ctxErr := range multiErr.Errors {
+		if have := [3]string{ctxErr.Get("pointer").(string), ctxErr.Get("schema").(string), ctxErr.Error()}; want[i] != have {
+			t.Errorf("error %d\nexpected: %#v\ngot:      %#v", i, want[i], have)
+		}
+	}
+
+	// undocumented status and operation
+	_, err = service.Retrieve("/posts/teapot").Expect(spec.Validate()).Do()
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "status 418 is not documented", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	_, err = service.Delete("/posts/post-1").Expect(spec.Validate()).Do()
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "no operation matches DELETE /api/posts/post-1", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// base path only matches whole segments
+	_, err = restit.NewHTTPTestService("/apiv2", handler).
+		Retrieve("/posts/post-1").
+		Expect(spec.Validate()).
+		Do()
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "no operation matches GET /apiv2/posts/post-1", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestOpenAPI_SmokeScenario(t *testing.T) {
+	spec, err := restit.ParseOpenAPI([]byte(dummyOpenAPI))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var requests []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "POST" {
+			w.Header().Set("Location", "/api/posts/"+body["id"].(string))
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(body)
+			return
+		}
+		w.Write([]byte(`{"id":"post-1","title":"hello"}`))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	if err := spec.SmokeScenario(service).Run(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "POST /api/posts,GET /api/posts/post-1", strings.Join(requests, ","); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// missing header is reported
+	handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"post-1","title":"hello"}`))
+	})
+	service = restit.NewHTTPTestService("/api", handler)
+	err = spec.SmokeScenario(service).Run()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `required header "Location" is missing`, err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
@@ -0,0 +1,90 @@
+package restit
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// rePathParam matches the named placeholders (e.g. "{id}")
+// in a path template. Variables (e.g. "{{id}}") are matched
+// as a whole so they can be skipped.
+var rePathParam = regexp.MustCompile(`\{\{\w+\}\}|\{\w+\}`)
+
+// absoluteURL returns the absolute URL of the request. Relative
+// URLs (e.g. of httptest services) are rendered with the request
+// Host, or localhost, over http.
+func absoluteURL(req *http.Request) string {
+	u := *req.URL
+	if !u.IsAbs() {
+		u.Scheme = "http"
+		if u.Host == "" {
+			u.Host = req.Host
+		}
+		if u.Host == "" {
+			u.Host = "localhost"
+		}
+	}
+	return u.String()
+}
+
+// joinPaths joins the escaped base path with the given paths.
+// Unlike path.Join, it keeps the escaped sequences and the
+// trailing slash. Query strings found in the paths are parsed
+// and returned separately.
+func joinPaths(base string, paths ...string) (joined string, query url.Values, err error) {
+	joined = base
+	for _, p := range paths {
+		if i := strings.Index(p, "?"); i >= 0 {
+			var q url.Values
+			if q, err = url.ParseQuery(p[i+1:]); err != nil {
+				return
+			}
+			if query == nil {
+				query = make(url.Values)
+			}
+			for key, vals := range q {
+				query[key] = append(query[key], vals...)
+			}
+			p = p[:i]
+		}
+		if p == "" {
+			continue
+		}
+		joined = strings.TrimRight(joined, "/") + "/" + strings.TrimLeft(p, "/")
+	}
+	return
+}
+
+// renderPath replaces the placeholders in the path template with
+// the escaped parameter values. Names of placeholders without
+// value are returned as missing.
+func renderPath(tmpl string, params map[string]string) (rendered string, missing []string) {
+	rendered = rePathParam.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		if strings.HasPrefix(placeholder, "{{") {
+			return placeholder
+		}
+		name := placeholder[1 : len(placeholder)-1]
+		if value, ok := params[name]; ok {
+			return url.PathEscape(value)
+		}
+		missing = append(missing, name)
+		return placeholder
+	})
+	return
+}
+
+// setPath sets the escaped path to the URL
+func setPath(u *url.URL, escaped string) (err error) {
+	unescaped, err := url.PathUnescape(escaped)
+	if err != nil {
+		return fmt.Errorf("invalid path %#v (%s)", escaped, err)
+	}
+	u.Path, u.RawPath = unescaped, ""
+	if u.EscapedPath() != escaped {
+		u.RawPath = escaped
+	}
+	return
+}
@@ -0,0 +1,90 @@
+package restit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Backoff returns the duration to wait before the next attempt.
+// The attempt number starts from 1.
+type Backoff func(attempt int) time.Duration
+
+// ConstantBackoff waits the same interval between attempts
+func ConstantBackoff(interval time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		return interval
+	}
+}
+
+// ExponentialBackoff waits initial duration after the first attempt
+// and multiply it by factor after every attempt, until it reaches max
+func ExponentialBackoff(initial, max time.Duration, factor float64) Backoff {
+	return func(attempt int) time.Duration {
+		d := time.Duration(float64(initial) * math.Pow(factor, float64(attempt-1)))
+		if d > max || d <= 0 {
+			return max
+		}
+		return d
+	}
+}
+
+// polling stores the options of Case.Eventually
+type polling struct {
+	timeout time.Duration
+	backoff Backoff
+}
+
+// Eventually makes Do re-issue the request until all the expectations
+// pass or the timeout expires. It waits the interval between attempts,
+// which can be changed by WithBackoff.
+func (c *Case) Eventually(timeout, interval time.Duration) *Case {
+	c.polling = &polling{
+		timeout: timeout,
+		backoff: ConstantBackoff(interval),
+	}
+	return c
+}
+
+// WithBackoff sets the Backoff between attempts of Eventually
+func (c *Case) WithBackoff(backoff Backoff) *Case {
+	if c.polling == nil {
+		return c.fail(fmt.Errorf("WithBackoff should be called after Eventually"))
+	}
+	c.polling.backoff = backoff
+	return c
+}
+
+// poll runs the case repeatedly until it passes or timeout.
+// The deadline also applies to the running attempt.
+func (c Case) poll() (resp Response, err error) {
+	deadline := time.Now().Add(c.polling.timeout)
+	ctx, cancel := context.WithDeadline(c.Context, deadline)
+	defer cancel()
+	c.Context = ctx
+
+	attempt := 1
+	for ; ; attempt++ {
+		if resp, err = c.do(); err == nil {
+			return
+		}
+
+		wait := c.polling.backoff(attempt)
+		if time.Now().Add(wait).After(deadline) {
+			break
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			continue
+		case <-c.Context.Done():
+			timer.Stop()
+		}
+		break
+	}
+
+	cErr := ExpandError(err)
+	cErr.Prepend("attempts", attempt)
+	return resp, cErr
+}
@@ -0,0 +1,148 @@
+package restit_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestCase_Eventually(t *testing.T) {
+	attempts := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		b, _ := ioutil.ReadAll(r.Body)
+		if attempts < 3 {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		w.Write(b)
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	resp, err := service.Create(map[string]string{"foo": "bar"}, "/jobs").
+		Eventually(time.Second, time.Millisecond).
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := 3, attempts; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := `{"foo":"bar"}`, resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestCase_Eventually_Timeout(t *testing.T) {
+	var bodies []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		w.WriteHeader(http.StatusAccepted)
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	// body of a manually constructed request is replayable
+	testCase := service.Create(nil, "/jobs").
+		Eventually(50*time.Millisecond, 10*time.Millisecond).
+		Expect(restit.StatusCodeIs(http.StatusOK))
+	testCase.Request.Body = ioutil.NopCloser(strings.NewReader("hello"))
+
+	_, err := testCase.Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	ctxErr, ok := err.(restit.ContextError)
+	if !ok {
+		t.Fatalf("expected restit.ContextError, got %#v", err)
+	}
+	if want, have := "expected 200, got 202", ctxErr.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if attempts, ok := ctxErr.Get("attempts").(int); !ok || attempts < 2 {
+		t.Errorf("unexpected attempts: %#v", ctxErr.Get("attempts"))
+	} else if want, have := strings.Repeat("hello,", attempts), strings.Join(bodies, ",")+","; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := fmt.Sprintf(`attempts=%d expectation=0 desc="status code is 200" ref="header status code" message="expected 200, got 202" curl="curl -X POST http://localhost/api/jobs --data-raw hello"`,
+		ctxErr.Get("attempts")), ctxErr.Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
+
+func TestCase_Eventually_HangingAttempt(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	start := time.Now()
+	_, err := service.Retrieve("/jobs/1").
+		Eventually(50*time.Millisecond, 10*time.Millisecond).
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to stop at the deadline, took %s", elapsed)
+	}
+	if want, have := "request timeout (context deadline exceeded)", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := 1, err.(restit.ContextError).Get("attempts"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestCase_Eventually_HTTPService(t *testing.T) {
+	var attempts, conns int32
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 20 {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		w.Write([]byte(`{"status":"pending"}`))
+	}))
+	testServer.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	testServer.Start()
+	defer testServer.Close()
+	service := restit.NewHTTPService(testServer.URL + "/api")
+
+	if _, err := service.Retrieve("/jobs/1").
+		Eventually(time.Second, time.Millisecond).
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Do(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// bodies of failed attempts are released for the connection to be reused
+	if want, have := int32(1), atomic.LoadInt32(&conns); want != have {
+		t.Errorf("expected %#v connections, got %#v", want, have)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := restit.ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond, 2)
+	for i, want := range []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+	} {
+		if have := backoff(i + 1); want != have {
+			t.Errorf("attempt %d: expected %s, got %s", i+1, want, have)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package restit
+
+import (
+	"net/http"
+)
+
+// Redacted replaces the redacted values in curl commands,
+// cassettes and HAR
+const Redacted = "[REDACTED]"
+
+// SensitiveHeaders are the headers redacted by default from curl
+// commands (see Case.Curl), cassettes of NewRecorder and HAR of NewHAR
+var SensitiveHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+	"X-Api-Key", "X-Auth-Token", "X-Csrf-Token", "X-Xsrf-Token",
+}
+
+// isSensitiveHeader tests if the header is in SensitiveHeaders
+func isSensitiveHeader(name string) bool {
+	for _, sensitive := range SensitiveHeaders {
+		if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSensitiveHeader returns Redacted for the headers in
+// SensitiveHeaders, or the value as-is. It is the default
+// HAR.RedactHeader of NewHAR.
+func RedactSensitiveHeader(name, value string) string {
+	if isSensitiveHeader(name) {
+		return Redacted
+	}
+	return value
+}
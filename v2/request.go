@@ -1,17 +1,34 @@
 package restit
 
 import (
-	"bytes"
-	"encoding/json"
 	"net/http"
 )
 
 // NewRequest generate a normal *http.Request with JSON-encoded payload
-// as request body
+// as request body. A nil payload produces a request without body.
 func NewRequest(method, urlString string, payload interface{}) (req *http.Request, err error) {
-	jbytes, err := json.Marshal(payload)
+	return NewRequestWith(method, urlString, payload, JSONEncoder)
+}
+
+// NewRequestWith generate a normal *http.Request with payload encoded
+// by the given BodyEncoder. The Content-Type header is set accordingly.
+// A nil encoder is treated as JSONEncoder.
+func NewRequestWith(method, urlString string, payload interface{}, enc BodyEncoder) (req *http.Request, err error) {
+	if enc == nil {
+		enc = JSONEncoder
+	}
+	body, contentType, err := enc.Encode(payload)
 	if err != nil {
 		return
 	}
-	return http.NewRequest(method, urlString, bytes.NewReader(jbytes))
+	if body == nil {
+		return http.NewRequest(method, urlString, nil)
+	}
+	if req, err = http.NewRequest(method, urlString, body); err != nil {
+		return
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return
 }
@@ -35,6 +35,9 @@ type Response interface {
 // and implements Response interface for it
 type HTTPTestResponse struct {
 	RawResponse *httptest.ResponseRecorder
+
+	// Timing is the duration of the handler
+	Timing *Timing
 }
 
 // StatusCode implements Response
@@ -81,6 +84,9 @@ func (r HTTPTestResponse) JSON() (lzjson.Node, error) {
 // and implements Response interface for it
 type HTTPResponse struct {
 	RawResponse *http.Response
+
+	// Timing is the timing of the request phases
+	Timing *Timing
 }
 
 // StatusCode implements Response
@@ -0,0 +1,152 @@
+package restit
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Scenario runs named steps (cases) in order, with setup and
+// teardown hooks and variables shared among the steps
+type Scenario struct {
+	Name string
+
+	// Vars is the variable store shared by all steps.
+	// If nil, steps use their own variable store.
+	Vars *Vars
+
+	// StopOnFailure skips the remaining steps after a step failed
+	StopOnFailure bool
+
+	steps    []step
+	setup    []func() error
+	teardown []func() error
+}
+
+// step is a named case in Scenario
+type step struct {
+	name string
+	c    *Case
+}
+
+// NewScenario creates a Scenario with a new variable store
+// which stops on failure
+func NewScenario(name string) *Scenario {
+	return &Scenario{
+		Name:          name,
+		Vars:          NewVars(),
+		StopOnFailure: true,
+	}
+}
+
+// Step appends a named case to the scenario
+func (s *Scenario) Step(name string, c *Case) *Scenario {
+	if s.Vars != nil {
+		c.WithVars(s.Vars)
+	}
+	s.steps = append(s.steps, step{name, c})
+	return s
+}
+
+// Setup appends a function to run before all steps
+func (s *Scenario) Setup(fn func() error) *Scenario {
+	s.setup = append(s.setup, fn)
+	return s
+}
+
+// Teardown appends a function to run after all steps,
+// even if any setup or step failed
+func (s *Scenario) Teardown(fn func() error) *Scenario {
+	s.teardown = append(s.teardown, fn)
+	return s
+}
+
+// runSetup runs all setup functions until one of them fails
+func (s *Scenario) runSetup() error {
+	for i, setup := range s.setup {
+		if err := setup(); err != nil {
+			cErr := ExpandError(err)
+			cErr.Prepend("setup", i)
+			cErr.Prepend("scenario", s.Name)
+			return cErr
+		}
+	}
+	return nil
+}
+
+// runTeardown runs all teardown functions and returns the
+// first error, if any
+func (s *Scenario) runTeardown() (err error) {
+	for i, teardown := range s.teardown {
+		if tErr := teardown(); tErr != nil && err == nil {
+			cErr := ExpandError(tErr)
+			cErr.Prepend("teardown", i)
+			cErr.Prepend("scenario", s.Name)
+			err = cErr
+		}
+	}
+	return
+}
+
+// Run runs the setup, all steps and the teardown in order.
+// It returns the first error with the failed step name.
+func (s *Scenario) Run() (err error) {
+	defer func() {
+		if tErr := s.runTeardown(); err == nil {
+			err = tErr
+		}
+	}()
+
+	if err = s.runSetup(); err != nil {
+		return
+	}
+	for _, step := range s.steps {
+		if _, sErr := step.c.Do(); sErr != nil {
+			cErr := ExpandError(sErr)
+			cErr.Prepend("step", step.name)
+			cErr.Prepend("scenario", s.Name)
+			if err == nil {
+				err = cErr
+			}
+			if s.StopOnFailure {
+				return
+			}
+		}
+	}
+	return
+}
+
+// Test runs the scenario with a subtest for each step. Failed
+// steps are logged with the ContextError key-value pairs.
+func (s *Scenario) Test(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if err := s.runTeardown(); err != nil {
+			logError(t, err)
+		}
+	}()
+
+	if err := s.runSetup(); err != nil {
+		logError(t, err)
+		t.FailNow()
+	}
+
+	failed := false
+	for i, step := range s.steps {
+		step := step
+		name := step.name
+		if name == "" {
+			name = fmt.Sprintf("step_%d", i)
+		}
+		if failed && s.StopOnFailure {
+			t.Run(name, func(t *testing.T) {
+				t.Skip("skipped after previous step failed")
+			})
+			continue
+		}
+		if !t.Run(name, func(t *testing.T) {
+			step.c.Test(t)
+		}) {
+			failed = true
+		}
+	}
+}
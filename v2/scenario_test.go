@@ -0,0 +1,100 @@
+package restit_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+// scenarioHandler is a dummy API which creates a post
+// and retrieves it
+func scenarioHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST":
+			w.Write([]byte(`{"id":"post-1"}`))
+		case r.URL.Path == "/api/post/post-1":
+			w.Write([]byte(`{"id":"post-1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func TestScenario_Test(t *testing.T) {
+	service := restit.NewHTTPTestService("/api", scenarioHandler())
+
+	var log []string
+	restit.NewScenario("create and retrieve").
+		Setup(func() error {
+			log = append(log, "setup")
+			return nil
+		}).
+		Teardown(func() error {
+			log = append(log, "teardown")
+			return nil
+		}).
+		Step("create", service.Create(nil, "/posts").
+			Expect(restit.StatusCodeIs(http.StatusOK)).
+			Capture("postID", "id")).
+		Step("retrieve", service.Retrieve("/post/{{postID}}").
+			Expect(restit.StatusCodeIs(http.StatusOK))).
+		Test(t)
+
+	if want, have := "setup,teardown", strings.Join(log, ","); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestScenario_Run(t *testing.T) {
+	service := restit.NewHTTPTestService("/api", scenarioHandler())
+
+	retrieved := false
+	scenario := restit.NewScenario("retrieve missing").
+		Teardown(func() error {
+			return fmt.Errorf("dummy teardown error")
+		}).
+		Step("retrieve missing", service.Retrieve("/post/missing").
+			Expect(restit.StatusCodeIs(http.StatusOK))).
+		Step("retrieve", service.Retrieve("/post/post-1").
+			AfterResponse(func(resp restit.Response) error {
+				retrieved = true
+				return nil
+			}))
+
+	err := scenario.Run()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `scenario="retrieve missing" step="retrieve missing" expectation=0 desc="status code is 200" ref="header status code" message="expected 200, got 404" curl="curl http://localhost/api/post/missing"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+	if retrieved {
+		t.Errorf("step should be skipped after failure")
+	}
+
+	// continue on failure
+	scenario.StopOnFailure = false
+	if err := scenario.Run(); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if !retrieved {
+		t.Errorf("step should run after failure")
+	}
+
+	// teardown error is returned if all steps pass
+	err = restit.NewScenario("teardown").
+		Teardown(func() error {
+			return fmt.Errorf("dummy teardown error")
+		}).
+		Run()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `scenario="teardown" teardown=0 message="dummy teardown error"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
@@ -0,0 +1,514 @@
+package restit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-restit/lzjson"
+)
+
+// Schema is a JSON Schema (draft 2020-12 subset, also compatible
+// with OpenAPI 3 schema objects)
+type Schema struct {
+	Ref  string             `json:"$ref,omitempty"`
+	Defs map[string]*Schema `json:"$defs,omitempty"`
+
+	Type     schemaType        `json:"type,omitempty"`
+	Nullable bool              `json:"nullable,omitempty"` // OpenAPI 3.0
+	Enum     []json.RawMessage `json:"enum,omitempty"`
+	Const    json.RawMessage   `json:"const,omitempty"`
+
+	// object
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+
+	// array
+	Items    *Schema `json:"items,omitempty"`
+	MinItems *int    `json:"minItems,omitempty"`
+	MaxItems *int    `json:"maxItems,omitempty"`
+
+	// string
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	Format    string `json:"format,omitempty"`
+
+	// number
+	Minimum          *float64        `json:"minimum,omitempty"`
+	Maximum          *float64        `json:"maximum,omitempty"`
+	ExclusiveMinimum json.RawMessage `json:"exclusiveMinimum,omitempty"` // number, or bool in OpenAPI 3.0
+	ExclusiveMaximum json.RawMessage `json:"exclusiveMaximum,omitempty"` // number, or bool in OpenAPI 3.0
+
+	// composition
+	AllOf []*Schema `json:"allOf,omitempty"`
+	AnyOf []*Schema `json:"anyOf,omitempty"`
+	OneOf []*Schema `json:"oneOf,omitempty"`
+
+	Example json.RawMessage `json:"example,omitempty"`
+	Default json.RawMessage `json:"default,omitempty"`
+
+	// boolean schema (true or false)
+	boolean *bool
+
+	// document to resolve "$ref" against
+	doc *schemaDocument
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts
+// boolean schemas.
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	switch string(bytes.TrimSpace(b)) {
+	case "true", "false":
+		v := string(bytes.TrimSpace(b)) == "true"
+		*s = Schema{boolean: &v}
+		return nil
+	}
+	type plain Schema
+	return json.Unmarshal(b, (*plain)(s))
+}
+
+// schemaType is the type keyword, which may be a string
+// or an array of strings
+type schemaType []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *schemaType) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = schemaType{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return err
+	}
+	*t = multiple
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (t schemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// pointerEscape escapes a JSON pointer reference token
+func pointerEscape(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+// pointerUnescape unescapes a JSON pointer reference token
+func pointerUnescape(token string) string {
+	return strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+}
+
+// schemaDocument is the root document of a schema (or an OpenAPI
+// spec) which "$ref" are resolved against
+type schemaDocument struct {
+	root  interface{}
+	mutex sync.Mutex
+	cache map[string]*Schema
+}
+
+// newSchemaDocument decodes the raw JSON document
+func newSchemaDocument(raw []byte) (doc *schemaDocument, err error) {
+	doc = &schemaDocument{cache: make(map[string]*Schema)}
+	if err = json.Unmarshal(raw, &doc.root); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// resolve decodes the value of the local reference (e.g.
+// "#/components/schemas/Post") into v
+func (doc *schemaDocument) resolve(ref string, v interface{}) (err error) {
+	if !strings.HasPrefix(ref, "#") {
+		return fmt.Errorf("unsupported reference %#v, only local references are supported", ref)
+	}
+	node := doc.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref[1:], "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = pointerUnescape(token)
+		switch n := node.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if node, ok = n[token]; !ok {
+				return fmt.Errorf("reference %#v not found", ref)
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return fmt.Errorf("reference %#v not found", ref)
+			}
+			node = n[i]
+		default:
+			return fmt.Errorf("reference %#v not found", ref)
+		}
+	}
+	raw, err := json.Marshal(node)
+	if err != nil {
+		return
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// schema resolves the schema reference with cache
+func (doc *schemaDocument) schema(ref string) (schema *Schema, err error) {
+	doc.mutex.Lock()
+	defer doc.mutex.Unlock()
+	if schema = doc.cache[ref]; schema != nil {
+		return
+	}
+	schema = &Schema{}
+	if err = doc.resolve(ref, schema); err != nil {
+		return nil, err
+	}
+	doc.cache[ref] = schema
+	return
+}
+
+// schemaViolation is a failure of schema validation
+type schemaViolation struct {
+	pointer       string // JSON pointer in the instance
+	schemaPointer string // JSON pointer in the schema document
+	message       string
+}
+
+// ContextError returns the violation as ContextError
+func (v schemaViolation) ContextError(ref string) ContextError {
+	ctxErr := NewContextError("%s", v.message)
+	ctxErr.Prepend("ref", ref)
+	pointer := v.pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	ctxErr.Append("pointer", pointer)
+	ctxErr.Append("schema", v.schemaPointer)
+	return ctxErr
+}
+
+// violationsError returns nil, a ContextError or a *MultiError
+// of the violations
+func violationsError(ref string, violations []schemaViolation) error {
+	switch len(violations) {
+	case 0:
+		return nil
+	case 1:
+		return violations[0].ContextError(ref)
+	}
+	errs := make([]ContextError, len(violations))
+	for i, violation := range violations {
+		errs[i] = violation.ContextError(ref)
+	}
+	return &MultiError{Errors: errs}
+}
+
+// schemaValidator validates JSON nodes against schemas
+type schemaValidator struct {
+	doc        *schemaDocument
+	violations []schemaViolation
+	resolving  map[string]bool // $ref being resolved at an instance pointer
+}
+
+// fail records a violation
+func (v *schemaValidator) fail(pointer, schemaPointer, format string, args ...interface{}) {
+	v.violations = append(v.violations, schemaViolation{
+		pointer:       pointer,
+		schemaPointer: schemaPointer,
+		message:       fmt.Sprintf(format, args...),
+	})
+}
+
+// matches tests if the node is valid against the schema
+// without recording violations
+func (v *schemaValidator) matches(schema *Schema, node lzjson.Node, pointer, schemaPointer string) bool {
+	sub := &schemaValidator{doc: v.doc, resolving: v.resolving}
+	sub.validate(schema, node, pointer, schemaPointer)
+	return len(sub.violations) == 0
+}
+
+// jsonType returns the JSON Schema type name of the node
+func jsonType(node lzjson.Node) string {
+	if node.Type() == lzjson.TypeNumber {
+		if n := node.Number(); n == math.Trunc(n) {
+			return "integer"
+		}
+	}
+	return typeName(node.Type())
+}
+
+// jsonEqual tests if the raw JSON values are equal
+func jsonEqual(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// reFormats are the regular expressions of string formats
+var reFormats = map[string]*regexp.Regexp{
+	"uuid": regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+}
+
+// validFormat tests the string against the format. Unknown
+// formats are always valid.
+func validFormat(format, str string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, str)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", str)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", str)
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(str)
+		return err == nil && addr.Address == str
+	case "uri":
+		u, err := url.Parse(str)
+		return err == nil && u.IsAbs()
+	case "ipv4":
+		ip := net.ParseIP(str)
+		return ip != nil && ip.To4() != nil && strings.Contains(str, ".")
+	case "ipv6":
+		ip := net.ParseIP(str)
+		return ip != nil && strings.Contains(str, ":")
+	}
+	if re, ok := reFormats[format]; ok {
+		return re.MatchString(str)
+	}
+	return true
+}
+
+// exclusiveLimit returns the exclusive limit of the keyword, which
+// may be a number, or a bool applying to the inclusive limit
+func exclusiveLimit(raw json.RawMessage, inclusive *float64) (limit float64, ok bool) {
+	if len(raw) == 0 {
+		return
+	}
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		if b && inclusive != nil {
+			return *inclusive, true
+		}
+		return
+	}
+	if err := json.Unmarshal(raw, &limit); err == nil {
+		return limit, true
+	}
+	return
+}
+
+// validate validates the node against the schema and records
+// all violations
+func (v *schemaValidator) validate(schema *Schema, node lzjson.Node, pointer, schemaPointer string) {
+	if schema == nil {
+		return
+	}
+	if schema.boolean != nil {
+		if !*schema.boolean {
+			v.fail(pointer, schemaPointer, "no value is allowed")
+		}
+		return
+	}
+	if schema.Ref != "" {
+		resolved, err := v.doc.schema(schema.Ref)
+		if err != nil {
+			v.fail(pointer, schemaPointer+"/$ref", "%s", err)
+			return
+		}
+		// a $ref resolving to itself without descending into the
+		// instance would never terminate
+		key := schema.Ref + " " + pointer
+		if v.resolving[key] {
+			v.fail(pointer, schemaPointer+"/$ref", "circular reference %#v", schema.Ref)
+			return
+		}
+		if v.resolving == nil {
+			v.resolving = make(map[string]bool)
+		}
+		v.resolving[key] = true
+		v.validate(resolved, node, pointer, schema.Ref)
+		delete(v.resolving, key)
+	}
+
+	// type
+	have := jsonType(node)
+	if have == "null" && schema.Nullable {
+		return
+	}
+	if len(schema.Type) > 0 {
+		valid := false
+		for _, want := range schema.Type {
+			if want == have || (want == "number" && have == "integer") {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			v.fail(pointer, schemaPointer+"/type", "expected type %s, got %s",
+				strings.Join(schema.Type, " or "), have)
+			return
+		}
+	}
+
+	// enum and const
+	if len(schema.Enum) > 0 {
+		valid := false
+		for _, value := range schema.Enum {
+			if jsonEqual(value, node.Raw()) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			values := make([]string, len(schema.Enum))
+			for i, value := range schema.Enum {
+				values[i] = string(value)
+			}
+			v.fail(pointer, schemaPointer+"/enum", "expected one of [%s], got %s",
+				strings.Join(values, ", "), bytes.TrimSpace(node.Raw()))
+		}
+	}
+	if len(schema.Const) > 0 && !jsonEqual(schema.Const, node.Raw()) {
+		v.fail(pointer, schemaPointer+"/const", "expected %s, got %s",
+			schema.Const, bytes.TrimSpace(node.Raw()))
+	}
+
+	switch node.Type() {
+	case lzjson.TypeObject:
+		v.validateObject(schema, node, pointer, schemaPointer)
+	case lzjson.TypeArray:
+		v.validateArray(schema, node, pointer, schemaPointer)
+	case lzjson.TypeString:
+		v.validateString(schema, node, pointer, schemaPointer)
+	case lzjson.TypeNumber:
+		v.validateNumber(schema, node, pointer, schemaPointer)
+	}
+
+	// composition
+	for i, sub := range schema.AllOf {
+		v.validate(sub, node, pointer, fmt.Sprintf("%s/allOf/%d", schemaPointer, i))
+	}
+	if len(schema.AnyOf) > 0 {
+		valid := false
+		for i, sub := range schema.AnyOf {
+			if v.matches(sub, node, pointer, fmt.Sprintf("%s/anyOf/%d", schemaPointer, i)) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			v.fail(pointer, schemaPointer+"/anyOf", "expected to match any of %d schemas, matched none",
+				len(schema.AnyOf))
+		}
+	}
+	if len(schema.OneOf) > 0 {
+		matched := 0
+		for i, sub := range schema.OneOf {
+			if v.matches(sub, node, pointer, fmt.Sprintf("%s/oneOf/%d", schemaPointer, i)) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.fail(pointer, schemaPointer+"/oneOf", "expected to match exactly one of %d schemas, matched %d",
+				len(schema.OneOf), matched)
+		}
+	}
+}
+
+// validateObject validates the properties of the object node
+func (v *schemaValidator) validateObject(schema *Schema, node lzjson.Node, pointer, schemaPointer string) {
+	for _, name := range schema.Required {
+		if t := node.Get(name).Type(); t == lzjson.TypeUndefined || t == lzjson.TypeError {
+			v.fail(pointer+"/"+pointerEscape(name), schemaPointer+"/required",
+				"required property %#v is missing", name)
+		}
+	}
+	keys := node.GetKeys()
+	sort.Strings(keys)
+	for _, name := range keys {
+		propPointer := pointer + "/" + pointerEscape(name)
+		if prop, ok := schema.Properties[name]; ok {
+			v.validate(prop, node.Get(name), propPointer,
+				schemaPointer+"/properties/"+pointerEscape(name))
+		} else if schema.AdditionalProperties != nil {
+			v.validate(schema.AdditionalProperties, node.Get(name), propPointer,
+				schemaPointer+"/additionalProperties")
+		}
+	}
+}
+
+// validateArray validates the length and the items of the array node
+func (v *schemaValidator) validateArray(schema *Schema, node lzjson.Node, pointer, schemaPointer string) {
+	n := node.Len()
+	if schema.MinItems != nil && n < *schema.MinItems {
+		v.fail(pointer, schemaPointer+"/minItems", "expected at least %d items, got %d", *schema.MinItems, n)
+	}
+	if schema.MaxItems != nil && n > *schema.MaxItems {
+		v.fail(pointer, schemaPointer+"/maxItems", "expected at most %d items, got %d", *schema.MaxItems, n)
+	}
+	if schema.Items != nil {
+		for i := 0; i < n; i++ {
+			v.validate(schema.Items, node.GetN(i), fmt.Sprintf("%s/%d", pointer, i), schemaPointer+"/items")
+		}
+	}
+}
+
+// validateString validates the length, pattern and format of
+// the string node
+func (v *schemaValidator) validateString(schema *Schema, node lzjson.Node, pointer, schemaPointer string) {
+	str := node.String()
+	n := len([]rune(str))
+	if schema.MinLength != nil && n < *schema.MinLength {
+		v.fail(pointer, schemaPointer+"/minLength", "expected length at least %d, got %d", *schema.MinLength, n)
+	}
+	if schema.MaxLength != nil && n > *schema.MaxLength {
+		v.fail(pointer, schemaPointer+"/maxLength", "expected length at most %d, got %d", *schema.MaxLength, n)
+	}
+	if schema.Pattern != "" {
+		if re, err := regexp.Compile(schema.Pattern); err != nil {
+			v.fail(pointer, schemaPointer+"/pattern", "invalid pattern %#v (%s)", schema.Pattern, err)
+		} else if !re.MatchString(str) {
+			v.fail(pointer, schemaPointer+"/pattern", "expected to match pattern %#v, got %#v", schema.Pattern, str)
+		}
+	}
+	if schema.Format != "" && !validFormat(schema.Format, str) {
+		v.fail(pointer, schemaPointer+"/format", "expected format %s, got %#v", schema.Format, str)
+	}
+}
+
+// validateNumber validates the range of the number node
+func (v *schemaValidator) validateNumber(schema *Schema, node lzjson.Node, pointer, schemaPointer string) {
+	n := node.Number()
+	if limit, ok := exclusiveLimit(schema.ExclusiveMinimum, schema.Minimum); ok && n <= limit {
+		v.fail(pointer, schemaPointer+"/exclusiveMinimum", "expected greater than %v, got %v", limit, n)
+	} else if schema.Minimum != nil && n < *schema.Minimum {
+		v.fail(pointer, schemaPointer+"/minimum", "expected at least %v, got %v", *schema.Minimum, n)
+	}
+	if limit, ok := exclusiveLimit(schema.ExclusiveMaximum, schema.Maximum); ok && n >= limit {
+		v.fail(pointer, schemaPointer+"/exclusiveMaximum", "expected less than %v, got %v", limit, n)
+	} else if schema.Maximum != nil && n > *schema.Maximum {
+		v.fail(pointer, schemaPointer+"/maximum", "expected at most %v, got %v", *schema.Maximum, n)
+	}
+}
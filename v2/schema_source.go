@@ -0,0 +1,228 @@
+package restit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ParseSchema parses the JSON Schema document
+func ParseSchema(data []byte) (schema *Schema, err error) {
+	schema = &Schema{}
+	if err = json.Unmarshal(data, schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema (%s)", err)
+	}
+	if schema.doc, err = newSchemaDocument(data); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema (%s)", err)
+	}
+	return
+}
+
+// LoadSchema loads the JSON Schema document from file
+func LoadSchema(path string) (schema *Schema, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	return ParseSchema(data)
+}
+
+// SchemaOf generates the JSON Schema of the Go value type, following
+// the encoding/json rules. Struct fields without "omitempty" are
+// required. Pointers are nullable. Named struct types are defined
+// in "$defs".
+func SchemaOf(v interface{}) *Schema {
+	gen := &schemaGenerator{
+		defs:  make(map[string]*Schema),
+		names: make(map[reflect.Type]string),
+	}
+	schema := gen.schema(reflect.TypeOf(v))
+	if len(gen.defs) > 0 {
+		schema.Defs = gen.defs
+	}
+	data, _ := json.Marshal(schema)
+	schema.doc, _ = newSchemaDocument(data)
+	return schema
+}
+
+// schemaGenerator generates schema of Go types
+type schemaGenerator struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+var (
+	typeTime       = reflect.TypeOf(time.Time{})
+	typeRawMessage = reflect.TypeOf(json.RawMessage{})
+	typeMarshaler  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schema returns the schema of the type
+func (gen *schemaGenerator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	switch {
+	case t == typeTime:
+		return &Schema{Type: schemaType{"string"}, Format: "date-time"}
+	case t == typeRawMessage || t.Implements(typeMarshaler):
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := gen.schema(t.Elem())
+		if len(schema.Type) > 0 {
+			schema.Type = append(schema.Type, "null")
+			return schema
+		}
+		if schema.Ref != "" {
+			return &Schema{AnyOf: []*Schema{schema, {Type: schemaType{"null"}}}}
+		}
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: schemaType{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: schemaType{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: schemaType{"number"}}
+	case reflect.String:
+		return &Schema{Type: schemaType{"string"}}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: schemaType{"string"}} // base64 encoded
+		}
+		schema := &Schema{Type: schemaType{"array"}, Items: gen.schema(t.Elem())}
+		if t.Kind() == reflect.Slice {
+			schema.Type = append(schema.Type, "null")
+		}
+		return schema
+	case reflect.Map:
+		return &Schema{
+			Type:                 schemaType{"object", "null"},
+			AdditionalProperties: gen.schema(t.Elem()),
+		}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return gen.structSchema(t)
+		}
+		name, ok := gen.names[t]
+		if !ok {
+			name = t.Name()
+			for i := 2; gen.defs[name] != nil; i++ {
+				name = fmt.Sprintf("%s%d", t.Name(), i)
+			}
+			gen.names[t] = name
+			gen.defs[name] = &Schema{} // placeholder for recursive types
+			gen.defs[name] = gen.structSchema(t)
+		}
+		return &Schema{Ref: "#/$defs/" + pointerEscape(name)}
+	}
+	return &Schema{}
+}
+
+// structSchema returns the object schema of the struct fields
+func (gen *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:       schemaType{"object"},
+		Properties: make(map[string]*Schema),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name, omitempty := parts[0], false
+		for _, opt := range parts[1:] {
+			omitempty = omitempty || opt == "omitempty"
+		}
+
+		// embedded struct without name is flattened
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := gen.structSchema(ft)
+				for key, prop := range embedded.Properties {
+					if _, ok := schema.Properties[key]; !ok {
+						schema.Properties[key] = prop
+					}
+				}
+				schema.Required = append(schema.Required, embedded.Required...)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = gen.schema(field.Type)
+		if !omitempty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// MarshalJSON implements json.Marshaler
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if s.boolean != nil {
+		return json.Marshal(*s.boolean)
+	}
+	type plain Schema
+	return json.Marshal((*plain)(s))
+}
+
+// MatchesSchema validates the JSON response body against the JSON
+// Schema. The schema may be a *Schema (see ParseSchema, LoadSchema
+// and SchemaOf), a JSON string or []byte, or a Go value which schema
+// is generated by SchemaOf. Every violation is reported with the
+// JSON pointer in the body ("pointer") and in the schema ("schema").
+func MatchesSchema(schema interface{}) Expectation {
+	var err error
+	var s *Schema
+	switch v := schema.(type) {
+	case *Schema:
+		s = v
+		if s.doc == nil {
+			data, _ := json.Marshal(s)
+			s.doc, err = newSchemaDocument(data)
+		}
+	case string:
+		s, err = ParseSchema([]byte(v))
+	case []byte:
+		s, err = ParseSchema(v)
+	default:
+		s = SchemaOf(schema)
+	}
+
+	return Describe(
+		"matches JSON schema",
+		func(ctx context.Context, resp Response) error {
+			if err != nil {
+				ctxErr := NewContextError("%s", err)
+				ctxErr.Prepend("ref", "schema")
+				return ctxErr
+			}
+			root, jsonErr := resp.JSON()
+			if jsonErr != nil {
+				ctxErr := NewContextError("response is not JSON (%s)", jsonErr)
+				ctxErr.Prepend("ref", "response")
+				return ctxErr
+			}
+			validator := &schemaValidator{doc: s.doc}
+			validator.validate(s, root, "", "#")
+			return violationsError("response.body", validator.violations)
+		})
+}
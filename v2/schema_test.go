@@ -0,0 +1,178 @@
+package restit_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func jsonResponse(body string) restit.Response {
+	return restit.CacheResponse(restit.HTTPResponse{
+		RawResponse: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		},
+	})
+}
+
+const dummySchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["id", "status", "author"],
+  "properties": {
+    "id": {"type": "string", "format": "uuid"},
+    "status": {"enum": ["draft", "published"]},
+    "rating": {"type": "number", "minimum": 0, "exclusiveMaximum": 5},
+    "slug": {"type": "string", "pattern": "^[a-z-]+$", "maxLength": 10},
+    "author": {"$ref": "#/$defs/author"},
+    "tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+    "link": {"oneOf": [{"type": "string", "format": "uri"}, {"type": "null"}]},
+    "cover": {"anyOf": [{"type": "string"}, {"type": "integer"}]}
+  },
+  "$defs": {
+    "author": {
+      "type": "object",
+      "required": ["email"],
+      "properties": {"email": {"type": "string", "format": "email"}}
+    }
+  }
+}`
+
+func TestMatchesSchema(t *testing.T) {
+	ctx := context.Background()
+	valid := `{
+		"id": "0b8f4d2e-3c4a-4b8e-9d1f-2a3b4c5d6e7f",
+		"status": "draft",
+		"rating": 4.5,
+		"slug": "hello",
+		"author": {"email": "foo@example.com"},
+		"tags": ["a"],
+		"link": null,
+		"cover": 1
+	}`
+	if err := restit.MatchesSchema(dummySchema).Do(ctx, jsonResponse(valid)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	invalid := `{
+		"id": "not-uuid",
+		"status": "deleted",
+		"rating": 5,
+		"slug": "Hello World",
+		"author": {},
+		"tags": ["a", "b", 3],
+		"link": "relative/path",
+		"cover": true
+	}`
+	err := restit.MatchesSchema(dummySchema).Do(ctx, jsonResponse(invalid))
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	want := []string{
+		`ref="response.body" message="required property \"email\" is missing" pointer="/author/email" schema="#/$defs/author/required"`,
+		`ref="response.body" message="expected to match any of 2 schemas, matched none" pointer="/cover" schema="#/properties/cover/anyOf"`,
+		`ref="response.body" message="expected format uuid, got \"not-uuid\"" pointer="/id" schema="#/properties/id/format"`,
+		`ref="response.body" message="expected to match exactly one of 2 schemas, matched 0" pointer="/link" schema="#/properties/link/oneOf"`,
+		`ref="response.body" message="expected less than 5, got 5" pointer="/rating" schema="#/properties/rating/exclusiveMaximum"`,
+		`ref="response.body" message="expected length at most 10, got 11" pointer="/slug" schema="#/properties/slug/maxLength"`,
+		`ref="response.body" message="expected to match pattern \"^[a-z-]+$\", got \"Hello World\"" pointer="/slug" schema="#/properties/slug/pattern"`,
+		`ref="response.body" message="expected one of [\"draft\", \"published\"], got \"deleted\"" pointer="/status" schema="#/properties/status/enum"`,
+		`ref="response.body" message="expected at most 2 items, got 3" pointer="/tags" schema="#/properties/tags/maxItems"`,
+		`ref="response.body" message="expected type string, got integer" pointer="/tags/2" schema="#/properties/tags/items/type"`,
+	}
+	have := strings.Split(err.(restit.ContextError).Log(), "\n")
+	if len(want) != len(have) {
+		t.Fatalf("expected %d violations, got:\n%s", len(want), strings.Join(have, "\n"))
+	}
+	for i := range want {
+		if want[i] != have[i] {
+			t.Errorf("violation %d\nexpected: %s\ngot:      %s", i, want[i], have[i])
+		}
+	}
+}
+
+func TestMatchesSchema_Recursive(t *testing.T) {
+	ctx := context.Background()
+	tree := `{
+	  "type": "object",
+	  "properties": {"children": {"type": "array", "items": {"$ref": "#"}}}
+	}`
+	if err := restit.MatchesSchema(tree).Do(ctx, jsonResponse(`{"children":[{"children":[]}]}`)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err := restit.MatchesSchema(tree).Do(ctx, jsonResponse(`{"children":[{"children":1}]}`))
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := `ref="response.body" message="expected type array, got integer" pointer="/children/0/children" schema="#/properties/children/type"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+
+	// a reference to itself never terminates
+	err = restit.MatchesSchema(`{"$ref": "#"}`).Do(ctx, jsonResponse(`{}`))
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := `ref="response.body" message="circular reference \"#\"" pointer="/" schema="#/$ref"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
+
+func TestMatchesSchema_Sources(t *testing.T) {
+	type Author struct {
+		Name string `json:"name"`
+	}
+	type Post struct {
+		ID       string   `json:"id"`
+		Title    string   `json:"title,omitempty"`
+		Author   *Author  `json:"author"`
+		Tags     []string `json:"tags"`
+		Rating   float64  `json:"rating"`
+		Private  bool     `json:"-"`
+		internal int
+	}
+	ctx := context.Background()
+
+	// from Go struct
+	if err := restit.MatchesSchema(Post{}).Do(ctx, jsonResponse(
+		`{"id":"1","author":{"name":"foo"},"tags":null,"rating":1}`)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err := restit.MatchesSchema(Post{}).Do(ctx, jsonResponse(
+		`{"id":1,"author":null,"tags":[1]}`))
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `ref="response.body" message="required property \"rating\" is missing" pointer="/rating" schema="#/$defs/Post/required"`+"\n"+
+		`ref="response.body" message="expected type string, got integer" pointer="/id" schema="#/$defs/Post/properties/id/type"`+"\n"+
+		`ref="response.body" message="expected type string, got integer" pointer="/tags/0" schema="#/$defs/Post/properties/tags/items/type"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+
+	// from file
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := ioutil.WriteFile(path, []byte(`{"type":"array","minItems":1}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	schema, err := restit.LoadSchema(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err = restit.MatchesSchema(schema).Do(ctx, jsonResponse(`[]`)); err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "expected at least 1 items, got 0", err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// invalid schema
+	if err = restit.MatchesSchema(`{"type":`).Do(ctx, jsonResponse(`[]`)); err == nil {
+		t.Errorf("expected error, got nil")
+	} else if want, have := "schema", err.(restit.ContextError).Get("ref"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
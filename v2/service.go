@@ -4,37 +4,85 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
-	"path"
+	"time"
 )
 
-// HTTPHandler implements CaseHandlerFunc
+// HTTPHandler implements CaseHandlerFunc with http.DefaultClient
 func HTTPHandler(req *http.Request) (resp Response, err error) {
-	c := &http.Client{}
-	rawResp, err := c.Do(req)
-	if err != nil {
+	return HTTPClientHandler(http.DefaultClient)(req)
+}
+
+// HTTPClientHandler implements CaseHandlerFunc with the given client
+func HTTPClientHandler(client *http.Client) func(*http.Request) (Response, error) {
+	return func(req *http.Request) (resp Response, err error) {
+		t := &tracer{start: time.Now()}
+		rawResp, err := client.Do(req.WithContext(t.withTrace(req.Context())))
+		if err != nil {
+			return
+		}
+
+		resp = HTTPResponse{RawResponse: rawResp, Timing: t.timing()}
 		return
 	}
-
-	resp = HTTPResponse{rawResp}
-	return
 }
 
 // NewHTTPService create a normal HTTP service to a real
-// HTTP server
-func NewHTTPService(rawURL string) *Service {
+// HTTP server. The http.Client used can be configured
+// by HTTPOption(s). Errors of the options are returned
+// by Case.Do.
+func NewHTTPService(rawURL string, options ...HTTPOption) *Service {
 	baseURL, _ := url.Parse(rawURL)
+	handler := CaseHandlerFunc(HTTPHandler)
+	if len(options) > 0 {
+		client, err := NewHTTPClient(options...)
+		if err != nil {
+			// a new error for every request, as the case appends
+			// to it
+			handler = func(req *http.Request) (Response, error) {
+				_, err := NewHTTPClient(options...)
+				return nil, err
+			}
+		} else {
+			handler = CaseHandlerFunc(HTTPClientHandler(client))
+		}
+	}
 	return &Service{
 		BaseURL: baseURL,
-		Handler: CaseHandlerFunc(HTTPHandler),
+		Handler: handler,
+		Vars:    NewVars(),
 	}
 }
 
 // HTTPTestHandler implements CaseHandlerFunc
 func HTTPTestHandler(handler http.Handler) func(*http.Request) (Response, error) {
 	return func(req *http.Request) (resp Response, err error) {
+		if req.Body == nil {
+			req.Body = http.NoBody
+		}
 		w := httptest.NewRecorder()
-		handler.ServeHTTP(w, req)
-		resp = HTTPTestResponse{w}
+
+		// run the handler in goroutine to respect
+		// the deadline of request context
+		start := time.Now()
+		done := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				done <- recover()
+			}()
+			handler.ServeHTTP(w, req)
+		}()
+		select {
+		case p := <-done:
+			if p != nil {
+				panic(p)
+			}
+		case <-req.Context().Done():
+			return nil, contextDoneError(req)
+		}
+		resp = HTTPTestResponse{
+			RawResponse: w,
+			Timing:      &Timing{Total: time.Since(start)},
+		}
 		return
 	}
 }
@@ -46,6 +94,7 @@ func NewHTTPTestService(rawURL string, handler http.Handler) *Service {
 	return &Service{
 		BaseURL: baseURL,
 		Handler: CaseHandlerFunc(HTTPTestHandler(handler)),
+		Vars:    NewVars(),
 	}
 }
 
@@ -54,9 +103,80 @@ func NewHTTPTestService(rawURL string, handler http.Handler) *Service {
 type Service struct {
 	BaseURL *url.URL
 	Handler CaseHandler
+
+	// Encoder encodes the payload of every new case.
+	// Defaults to JSONEncoder if nil.
+	Encoder BodyEncoder
+
+	// Header and Query are added to every new case by default
+	Header http.Header
+	Query  url.Values
+
+	// RequestHooks and ResponseHooks are added to every new case
+	// and run in order by Case.Do
+	RequestHooks  []RequestHook
+	ResponseHooks []ResponseHook
+
+	// Auth authenticates the request of every new case
+	Auth Authenticator
+
+	// Observers observe the request and response of every new case
+	Observers []Observer
+
+	// Session, if not nil, persists cookies and CSRF token
+	// across the cases
+	Session *Session
+
+	// Vars stores variables captured by cases, to be
+	// referenced by later cases
+	Vars *Vars
+
+	// SoftAssert makes every new case run all expectations
+	// and report all failures
+	SoftAssert bool
+
+	// ShowSecrets makes the curl commands of every new case
+	// include the values of the SensitiveHeaders
+	ShowSecrets bool
+}
+
+// AddHeader add given header key-value pair to every new case
+func (s *Service) AddHeader(key, value string) *Service {
+	if s.Header == nil {
+		s.Header = make(http.Header)
+	}
+	s.Header.Add(key, value)
+	return s
+}
+
+// AddQuery add given query key-value pair to every new case
+func (s *Service) AddQuery(key, value string) *Service {
+	if s.Query == nil {
+		s.Query = make(url.Values)
+	}
+	s.Query.Add(key, value)
+	return s
+}
+
+// BeforeRequest appends a hook to run on the request of
+// every new case before it is handled
+func (s *Service) BeforeRequest(hook RequestHook) *Service {
+	s.RequestHooks = append(s.RequestHooks, hook)
+	return s
+}
+
+// AfterResponse appends a hook to run on the response of
+// every new case before the expectations
+func (s *Service) AfterResponse(hook ResponseHook) *Service {
+	s.ResponseHooks = append(s.ResponseHooks, hook)
+	return s
 }
 
 // NewCase creates a new Case struct with
+//
+// The paths are joined to the BaseURL path as-is. They may contain
+// escaped sequences, query strings and named placeholders like "{id}",
+// which should be filled by Case.WithPathParam.
 func (s Service) NewCase(method string, payload interface{}, paths ...string) *Case {
 
 	// formulate request URL
@@ -64,19 +184,60 @@ func (s Service) NewCase(method string, payload interface{}, paths ...string) *C
 	if err != nil {
 		panic(err)
 	}
-	if len(paths) > 0 {
-		requestURL.Path = path.Join(append([]string{requestURL.Path}, paths...)...)
+	pathTmpl, query, err := joinPaths(requestURL.EscapedPath(), paths...)
+	if err != nil {
+		panic(err)
+	}
+	if err = setPath(requestURL, pathTmpl); err != nil {
+		panic(err)
+	}
+	if len(s.Query) > 0 || len(query) > 0 {
+		q := requestURL.Query()
+		for key, vals := range s.Query {
+			q[key] = append(q[key], vals...)
+		}
+		for key, vals := range query {
+			q[key] = append(q[key], vals...)
+		}
+		requestURL.RawQuery = q.Encode()
 	}
 
 	// formulate request
-	req, err := NewRequest(method, requestURL.String(), payload)
-	if err != nil {
+	enc := s.Encoder
+	if enc == nil {
+		enc = JSONEncoder
+	}
+	// encoding error is returned by Case.Do, unless the
+	// payload is re-encoded with Case.WithEncoder
+	req, encodeErr := NewRequestWith(method, requestURL.String(), payload, enc)
+	if encodeErr != nil {
+		if req, err = http.NewRequest(method, requestURL.String(), nil); err != nil {
+			panic(err)
+		}
+	}
+
+	if err = setPath(req.URL, pathTmpl); err != nil {
 		panic(err)
 	}
+	for key, vals := range s.Header {
+		req.Header[key] = append(req.Header[key], vals...)
+	}
 
 	return &Case{
-		Request: req,
-		Handler: s.Handler,
+		Request:       req,
+		Handler:       s.Handler,
+		payload:       payload,
+		encoder:       enc,
+		encodeErr:     encodeErr,
+		pathTmpl:      pathTmpl,
+		requestHooks:  append([]RequestHook(nil), s.RequestHooks...),
+		responseHooks: append([]ResponseHook(nil), s.ResponseHooks...),
+		auth:          s.Auth,
+		observers:     append([]Observer(nil), s.Observers...),
+		session:       s.Session,
+		vars:          s.Vars,
+		soft:          s.SoftAssert,
+		showSecrets:   s.ShowSecrets,
 	}
 }
 
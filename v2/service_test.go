@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -220,3 +221,151 @@ func TestHTTPTestService(t *testing.T) {
 		t.Errorf("failed running test suite: %s", err.Error())
 	}
 }
+
+func TestService_PathTemplate(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RequestURI()))
+	})
+	service := restit.NewHTTPTestService("/api/?access_token=foo", handler)
+
+	tests := []struct {
+		desc string
+		c    *restit.Case
+		uri  string
+	}{
+		{
+			desc: "path param escaped",
+			c:    service.Retrieve("/post/{id}").WithPathParam("id", "hello/world"),
+			uri:  "/api/post/hello%2Fworld?access_token=foo",
+		},
+		{
+			desc: "trailing slash preserved",
+			c:    service.List("/posts/"),
+			uri:  "/api/posts/?access_token=foo",
+		},
+		{
+			desc: "escaped segment preserved",
+			c:    service.Retrieve("/post/a%2Fb"),
+			uri:  "/api/post/a%2Fb?access_token=foo",
+		},
+		{
+			desc: "query string in path",
+			c:    service.List("/posts?page=2"),
+			uri:  "/api/posts?access_token=foo&page=2",
+		},
+		{
+			desc: "multiple params",
+			c: service.Retrieve("/user/{user}/post/{id}").
+				WithPathParam("user", "john").
+				WithPathParam("id", 42),
+			uri: "/api/user/john/post/42?access_token=foo",
+		},
+	}
+
+	for _, test := range tests {
+		resp, err := test.c.Do()
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %s", test.desc, err)
+			continue
+		}
+		if want, have := test.uri, resp.String(); want != have {
+			t.Errorf("[%s] expected %#v, got %#v", test.desc, want, have)
+		}
+	}
+}
+
+func TestService_PathTemplate_Missing(t *testing.T) {
+	service := restit.NewHTTPTestService("/api", dummyServiceHandler())
+	_, err := service.Retrieve("/user/{user}/post/{id}").
+		WithPathParam("user", "john").
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if want, have := `path parameter "id" is not filled`, err.Error(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := `ref="request.path" message="path parameter \"id\" is not filled" path="/api/user/{user}/post/{id}"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
+
+func TestService_Defaults(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth":  r.Header.Get("Authorization"),
+			"agent": r.Header.Get("User-Agent"),
+			"token": r.URL.Query()["access_token"],
+			"hook":  r.Header.Get("X-Hook"),
+		})
+	})
+
+	var hookRun []string
+	service := restit.NewHTTPTestService("/api", handler).
+		AddHeader("Authorization", "Bearer foo").
+		AddHeader("User-Agent", "RESTit tester").
+		AddQuery("access_token", "foo").
+		BeforeRequest(func(req *http.Request) error {
+			hookRun = append(hookRun, "before 1")
+			req.Header.Set("X-Hook", "hooked")
+			return nil
+		}).
+		AfterResponse(func(resp restit.Response) error {
+			hookRun = append(hookRun, "after 1")
+			return nil
+		})
+
+	resp, err := service.Retrieve("/post/1").Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := `{"agent":"RESTit tester","auth":"Bearer foo","hook":"hooked","token":["foo"]}`+"\n",
+		resp.String(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+	if want, have := "before 1,after 1", strings.Join(hookRun, ","); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// override and remove defaults in a case
+	resp, err = service.Retrieve("/post/1").
+		SetHeader("Authorization", "Bearer bar").
+		DelHeader("User-Agent").
+		SetQuery("access_token", "bar").
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := `{"agent":"","auth":"Bearer bar","hook":"hooked","token":["bar"]}`+"\n",
+		resp.String(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
+
+func TestService_HookError(t *testing.T) {
+	service := restit.NewHTTPTestService("/api", dummyServiceHandler())
+
+	_, err := service.Retrieve("/post/1").
+		BeforeRequest(func(req *http.Request) error {
+			return fmt.Errorf("dummy before error")
+		}).
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `before_request=0 message="dummy before error"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+
+	service.AfterResponse(func(resp restit.Response) error {
+		return fmt.Errorf("dummy after error")
+	})
+	_, err = service.Retrieve("/post/1").Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `after_response=0 message="dummy after error"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
@@ -0,0 +1,126 @@
+package restit
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+
+	"github.com/go-restit/lzjson"
+)
+
+// Session persists cookies and CSRF token across the cases
+// of a Service. It works with both HTTP and httptest based
+// services.
+type Session struct {
+	Jar http.CookieJar
+
+	mutex sync.Mutex
+	csrf  []csrfRule
+	token string
+}
+
+// csrfRule defines where to capture the CSRF token from
+// and which header to send it with
+type csrfRule struct {
+	respHeader string
+	jsonField  string
+	reqHeader  string
+}
+
+// NewSession creates a Session with an empty cookie jar
+func NewSession() *Session {
+	jar, _ := cookiejar.New(nil)
+	return &Session{Jar: jar}
+}
+
+// CSRFFromHeader captures CSRF token from the response header
+// respHeader and sends it back with the request header reqHeader
+func (s *Session) CSRFFromHeader(respHeader, reqHeader string) *Session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.csrf = append(s.csrf, csrfRule{respHeader: respHeader, reqHeader: reqHeader})
+	return s
+}
+
+// CSRFFromJSON captures CSRF token from the field (a JSON path like
+// "meta.csrf_token", see LengthIs) of the JSON response and sends it
+// back with the request header reqHeader
+func (s *Session) CSRFFromJSON(field, reqHeader string) *Session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.csrf = append(s.csrf, csrfRule{jsonField: field, reqHeader: reqHeader})
+	return s
+}
+
+// CSRFToken returns the last captured CSRF token
+func (s *Session) CSRFToken() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.token
+}
+
+// sessionURL returns an absolute URL of the request for the cookie jar.
+// Relative URLs (e.g. of httptest services) and TLS requests are
+// treated as https so Secure cookies are kept.
+func sessionURL(req *http.Request) *url.URL {
+	if req.URL.IsAbs() && req.TLS == nil {
+		return req.URL
+	}
+	u := *req.URL
+	u.Scheme = "https"
+	if u.Host == "" {
+		u.Host = req.Host
+	}
+	if u.Host == "" {
+		u.Host = "localhost"
+	}
+	return &u
+}
+
+// apply adds the session cookies and CSRF token to the request
+func (s *Session) apply(req *http.Request) {
+	if s.Jar != nil {
+		for _, cookie := range s.Jar.Cookies(sessionURL(req)) {
+			req.AddCookie(cookie)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.token == "" {
+		return
+	}
+	for _, rule := range s.csrf {
+		req.Header.Set(rule.reqHeader, s.token)
+	}
+}
+
+// update stores the cookies and CSRF token from the response
+func (s *Session) update(req *http.Request, resp Response) {
+	if s.Jar != nil {
+		rawResp := http.Response{Header: resp.Header()}
+		if cookies := rawResp.Cookies(); len(cookies) > 0 {
+			s.Jar.SetCookies(sessionURL(req), cookies)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, rule := range s.csrf {
+		if rule.respHeader != "" {
+			if token := resp.Header().Get(rule.respHeader); token != "" {
+				s.token = token
+			}
+			continue
+		}
+		root, err := resp.JSON()
+		if err != nil {
+			continue
+		}
+		node, pathErr := lookupPath(root, rule.jsonField)
+		if pathErr == nil && node.Type() == lzjson.TypeString && node.String() != "" {
+			s.token = node.String()
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package restit_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+// sessionHandler is a dummy web API with session cookie and CSRF token
+func sessionHandler(secure bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session-1", Path: "/", Secure: secure})
+		w.Header().Set("X-CSRF-Token", "header-token")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"meta": map[string]interface{}{
+				"csrf":   "json-token",
+				"tokens": []string{"path-token"},
+			},
+		})
+	})
+	mux.HandleFunc("/api/post", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "session-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-CSRF-Token")))
+	})
+	return mux
+}
+
+func TestSession(t *testing.T) {
+	testServer := httptest.NewServer(sessionHandler(false))
+	defer testServer.Close()
+
+	tests := []struct {
+		desc    string
+		service *restit.Service
+		session *restit.Session
+		token   string
+	}{
+		{
+			desc:    "httptest, CSRF from header",
+			service: restit.NewHTTPTestService("/api", sessionHandler(false)),
+			session: restit.NewSession().CSRFFromHeader("X-CSRF-Token", "X-CSRF-Token"),
+			token:   "header-token",
+		},
+		{
+			desc:    "http, CSRF from JSON",
+			service: restit.NewHTTPService(testServer.URL + "/api"),
+			session: restit.NewSession().CSRFFromJSON("meta.csrf", "X-CSRF-Token"),
+			token:   "json-token",
+		},
+		{
+			desc:    "httptest, secure cookie, CSRF from JSON path",
+			service: restit.NewHTTPTestService("//api.example.com/api", sessionHandler(true)),
+			session: restit.NewSession().CSRFFromJSON("meta.tokens[0]", "X-CSRF-Token"),
+			token:   "path-token",
+		},
+	}
+
+	for _, test := range tests {
+		service := test.service
+
+		// without session
+		if _, err := service.Create(nil, "/login").Do(); err != nil {
+			t.Errorf("[%s] unexpected error: %s", test.desc, err)
+		}
+		if _, err := service.Retrieve("/post").
+			Expect(restit.StatusCodeIs(http.StatusUnauthorized)).
+			Do(); err != nil {
+			t.Errorf("[%s] unexpected error: %s", test.desc, err)
+		}
+
+		// with session
+		service.Session = test.session
+		if _, err := service.Create(nil, "/login").Do(); err != nil {
+			t.Errorf("[%s] unexpected error: %s", test.desc, err)
+		}
+		testCase := service.Retrieve("/post").
+			Expect(restit.StatusCodeIs(http.StatusOK))
+		for i := 0; i < 2; i++ {
+			resp, err := testCase.Do()
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %s", test.desc, err)
+				continue
+			}
+			if want, have := test.token, resp.String(); want != have {
+				t.Errorf("[%s] expected %#v, got %#v", test.desc, want, have)
+			}
+		}
+		if want, have := test.token, test.session.CSRFToken(); want != have {
+			t.Errorf("[%s] expected %#v, got %#v", test.desc, want, have)
+		}
+		if want, have := "", testCase.Request.Header.Get("Cookie"); want != have {
+			t.Errorf("[%s] case request modified: expected %#v, got %#v", test.desc, want, have)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package restit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Stub implements CaseHandler with canned responses by route.
+// Use it in place of HTTPHandler to test code consuming an API:
+//
+//	stub := restit.NewStub()
+//	stub.On("GET", "/api/post/{id}").Reply(200, post).Times(2)
+//	service.Handler = stub
+//	...
+//	if err := stub.Verify(); err != nil {
+//		t.Error(err)
+//	}
+type Stub struct {
+	mutex      sync.Mutex
+	routes     []*StubRoute
+	unexpected []ContextError
+}
+
+// NewStub creates an empty Stub
+func NewStub() *Stub {
+	return &Stub{}
+}
+
+// StubRoute is a canned response of a Stub
+type StubRoute struct {
+	method string
+	path   string
+	re     *regexp.Regexp
+	status int
+	header http.Header
+	body   []byte
+	err    error
+	times  int
+	calls  int
+}
+
+// On registers a route of the method and path. The path may contain
+// named placeholders (e.g. "/post/{id}") which match a path segment.
+// Routes are matched against the escaped request path, so a segment
+// with escaped slash (e.g. "a%2Fb") matches a placeholder.
+// The route replies 200 with empty body unless Reply is called.
+func (s *Stub) On(method, path string) *StubRoute {
+	pattern := "^"
+	last := 0
+	for _, loc := range rePathParam.FindAllStringIndex(path, -1) {
+		pattern += regexp.QuoteMeta(path[last:loc[0]]) + "[^/]+"
+		last = loc[1]
+	}
+	pattern += regexp.QuoteMeta(path[last:]) + "$"
+
+	route := &StubRoute{
+		method: strings.ToUpper(method),
+		path:   path,
+		re:     regexp.MustCompile(pattern),
+		status: http.StatusOK,
+		header: make(http.Header),
+		times:  -1,
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.routes = append(s.routes, route)
+	return route
+}
+
+// Reply sets the status code and body of the response. The body
+// may be a string, []byte, or any value to be encoded as JSON.
+func (r *StubRoute) Reply(status int, body interface{}) *StubRoute {
+	r.status = status
+	switch b := body.(type) {
+	case nil:
+		r.body = nil
+	case string:
+		r.body = []byte(b)
+	case []byte:
+		r.body = b
+	default:
+		r.body, r.err = json.Marshal(b)
+		if r.header.Get("Content-Type") == "" {
+			r.header.Set("Content-Type", "application/json")
+		}
+	}
+	return r
+}
+
+// WithHeader sets a header of the response
+func (r *StubRoute) WithHeader(key, value string) *StubRoute {
+	r.header.Set(key, value)
+	return r
+}
+
+// Times sets the number of times the route is expected to be called.
+// The route does not match more calls than that. Routes without
+// Times match any number of calls.
+func (r *StubRoute) Times(n int) *StubRoute {
+	r.times = n
+	return r
+}
+
+// desc returns the description of the route for error messages
+func (r *StubRoute) desc() string {
+	return r.method + " " + r.path
+}
+
+// Handle implements CaseHandler
+func (s *Stub) Handle(req *http.Request) (resp Response, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var exhausted *StubRoute
+	for _, route := range s.routes {
+		if route.method != req.Method || !route.re.MatchString(req.URL.EscapedPath()) {
+			continue
+		}
+		if route.times >= 0 && route.calls >= route.times {
+			exhausted = route
+			continue
+		}
+		route.calls++
+		if route.err != nil {
+			ctxErr := NewContextError("unable to encode reply (%s)", route.err)
+			ctxErr.Prepend("ref", "stub."+route.desc())
+			return nil, ctxErr
+		}
+
+		w := httptest.NewRecorder()
+		for key, vals := range route.header {
+			w.Header()[key] = append([]string(nil), vals...)
+		}
+		w.WriteHeader(route.status)
+		w.Write(route.body)
+		return HTTPTestResponse{RawResponse: w}, nil
+	}
+
+	var ctxErr ContextError
+	if exhausted != nil {
+		ctxErr = NewContextError("unexpected request, route called more than %d times", exhausted.times)
+		ctxErr.Prepend("ref", "stub."+exhausted.desc())
+	} else {
+		ctxErr = NewContextError("unexpected request, no route matches")
+		ctxErr.Prepend("ref", "stub")
+	}
+	ctxErr.Append("method", req.Method)
+	ctxErr.Append("url", req.URL.String())
+	s.unexpected = append(s.unexpected, ctxErr)
+	return nil, ctxErr
+}
+
+// Verify returns a *MultiError of the unexpected calls and the
+// routes not called the expected number of times, or nil if
+// there is none
+func (s *Stub) Verify() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	errs := append([]ContextError(nil), s.unexpected...)
+	for _, route := range s.routes {
+		if route.times >= 0 && route.calls != route.times {
+			ctxErr := NewContextError("expected %d calls, got %d", route.times, route.calls)
+			ctxErr.Prepend("ref", "stub."+route.desc())
+			errs = append(errs, ctxErr)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &MultiError{Errors: errs}
+}
@@ -0,0 +1,98 @@
+package restit_test
+
+import (
+	"net/http"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestStub(t *testing.T) {
+	stub := restit.NewStub()
+	stub.On("GET", "/api/post/{id}").
+		Reply(http.StatusOK, map[string]string{"id": "1"}).
+		Times(2)
+	stub.On("DELETE", "/api/post/{id}").
+		Reply(http.StatusNoContent, nil).
+		WithHeader("X-Deleted", "true")
+	service := restit.NewHTTPService("/api")
+	service.Handler = stub
+
+	for i := 0; i < 2; i++ {
+		resp, err := service.Retrieve("/post/{id}").
+			WithPathParam("id", i).
+			Expect(restit.StatusCodeIs(http.StatusOK)).
+			Do()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if want, have := `{"id":"1"}`, resp.String(); want != have {
+			t.Errorf("expected %#v, got %#v", want, have)
+		}
+		if want, have := "application/json", resp.Header().Get("Content-Type"); want != have {
+			t.Errorf("expected %#v, got %#v", want, have)
+		}
+	}
+	if err := stub.Verify(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// called more than expected
+	_, err := service.Retrieve("/post/3").Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `ref="stub.GET /api/post/{id}" message="unexpected request, route called more than 2 times" method="GET" url="/api/post/3" curl="curl http://localhost/api/post/3"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+
+	// no route matches
+	if _, err = service.Retrieve("/post/3/comments").Do(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	resp, err := service.Delete("/post/3").
+		Expect(restit.StatusCodeIs(http.StatusNoContent)).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "true", resp.Header().Get("X-Deleted"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	err = stub.Verify()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if want, have := 2, len(err.(*restit.MultiError).Errors); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// route not called the expected times
+	stub = restit.NewStub()
+	stub.On("GET", "/api/posts").Times(1)
+	if err = stub.Verify(); err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `ref="stub.GET /api/posts" message="expected 1 calls, got 0"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
+
+func TestStub_EscapedPath(t *testing.T) {
+	stub := restit.NewStub()
+	stub.On("GET", "/api/file/{name}").Reply(http.StatusOK, "file")
+	service := restit.NewHTTPService("/api")
+	service.Handler = stub
+
+	resp, err := service.Retrieve("/file/{name}").
+		WithPathParam("name", "a/b").
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "file", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
@@ -0,0 +1,73 @@
+package restit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// maxReportBody is the maximum length of response body
+// shown in test failure report
+const maxReportBody = 1024
+
+// Test runs the case and reports any failure to t with the
+// request line, the failed expectation and the response body.
+// It returns the response so the chain can continue.
+func (c *Case) Test(t testing.TB) Response {
+	t.Helper()
+	resp, err := c.Do()
+	if err != nil {
+		t.Error(c.report(resp, err))
+	}
+	return resp
+}
+
+// Require is like Test, but stops the test with t.FailNow
+// on failure
+func (c *Case) Require(t testing.TB) Response {
+	t.Helper()
+	resp, err := c.Do()
+	if err != nil {
+		t.Error(c.report(resp, err))
+		t.FailNow()
+	}
+	return resp
+}
+
+// report formats the failure of the case for test log
+func (c *Case) report(resp Response, err error) string {
+	lines := []string{err.Error()}
+	if c.Request != nil {
+		lines = append(lines, fmt.Sprintf("request: %s %s", c.Request.Method, c.Request.URL))
+	}
+	cErrs := []ContextError{}
+	if mErr, ok := err.(*MultiError); ok {
+		cErrs = mErr.Errors
+	} else if cErr, ok := err.(ContextError); ok {
+		cErrs = append(cErrs, cErr)
+	}
+	for _, cErr := range cErrs {
+		if i := cErr.Get("expectation"); i != nil {
+			lines = append(lines, fmt.Sprintf("expectation #%v: %v", i, cErr.Get("desc")))
+		}
+		lines = append(lines, "context: "+cErr.Log())
+	}
+	if resp != nil {
+		body := resp.String()
+		if len(body) > maxReportBody {
+			body = fmt.Sprintf("%s... (%d bytes truncated)", body[:maxReportBody], len(body)-maxReportBody)
+		}
+		lines = append(lines, fmt.Sprintf("response: %d %s", resp.StatusCode(), body))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// logError logs the ContextError key-value pairs, if any,
+// and marks the test as failed
+func logError(t testing.TB, err error) {
+	t.Helper()
+	if cErr, ok := err.(ContextError); ok {
+		t.Log(cErr.Log())
+	}
+	t.Error(err.Error())
+}
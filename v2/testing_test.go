@@ -0,0 +1,83 @@
+package restit_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+// fakeTB records the failures reported to testing.TB
+type fakeTB struct {
+	testing.TB
+	errors  []string
+	failNow bool
+}
+
+func (t *fakeTB) Helper() {}
+
+func (t *fakeTB) Error(args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprint(args...))
+}
+
+func (t *fakeTB) FailNow() {
+	t.failNow = true
+}
+
+func TestCase_Test(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(strings.Repeat("x", 2000)))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	// passing case
+	tb := &fakeTB{}
+	resp := service.Retrieve("/post/1").
+		Expect(restit.StatusCodeIs(http.StatusNotFound)).
+		Test(tb)
+	if len(tb.errors) > 0 {
+		t.Errorf("unexpected errors: %#v", tb.errors)
+	}
+	if want, have := http.StatusNotFound, resp.StatusCode(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// failing case
+	tb = &fakeTB{}
+	service.Retrieve("/post/1").
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Test(tb)
+	if want, have := 1, len(tb.errors); want != have {
+		t.Fatalf("expected %#v, got %#v", want, have)
+	}
+	if tb.failNow {
+		t.Errorf("Test should not call FailNow")
+	}
+	lines := strings.Split(tb.errors[0], "\n")
+	if want, have := 5, len(lines); want != have {
+		t.Fatalf("expected %#v, got %#v", want, have)
+	}
+	for i, want := range []string{
+		"expected 200, got 404",
+		"request: GET /api/post/1",
+		"expectation #0: status code is 200",
+		`context: expectation=0 desc="status code is 200" ref="header status code" message="expected 200, got 404" curl="curl http://localhost/api/post/1"`,
+		"response: 404 " + strings.Repeat("x", 1024) + "... (976 bytes truncated)",
+	} {
+		if have := lines[i]; want != have {
+			t.Errorf("line %d\nexpected: %s\ngot:      %s", i, want, have)
+		}
+	}
+
+	// require
+	tb = &fakeTB{}
+	service.Retrieve("/post/1").
+		Expect(restit.StatusCodeIs(http.StatusOK)).
+		Require(tb)
+	if !tb.failNow {
+		t.Errorf("Require should call FailNow")
+	}
+}
@@ -0,0 +1,160 @@
+package restit
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing is the timing metadata of a response. Phases which do not
+// apply to the handler (e.g. DNS for HTTPTestHandler) are zero.
+type Timing struct {
+
+	// Total is the duration from sending the request to receiving
+	// the response header (HTTPHandler), or the duration of the
+	// http.Handler (HTTPTestHandler)
+	Total time.Duration
+
+	// DNS is the duration of DNS lookup
+	DNS time.Duration
+
+	// Connect is the duration of establishing TCP connection
+	Connect time.Duration
+
+	// TLS is the duration of TLS handshake
+	TLS time.Duration
+
+	// FirstByte is the duration from sending the request
+	// to receiving the first response byte
+	FirstByte time.Duration
+}
+
+// appendTo appends the non-zero phases to the ContextError
+func (t *Timing) appendTo(ctxErr ContextError) {
+	phases := []struct {
+		key string
+		d   time.Duration
+	}{
+		{"total", t.Total},
+		{"dns", t.DNS},
+		{"connect", t.Connect},
+		{"tls", t.TLS},
+		{"first_byte", t.FirstByte},
+	}
+	for _, phase := range phases {
+		if phase.d > 0 {
+			ctxErr.Append(phase.key, phase.d.String())
+		}
+	}
+}
+
+// TimingOf returns the timing metadata of the response, or nil
+// if the response has none
+func TimingOf(resp Response) *Timing {
+	switch r := resp.(type) {
+	case HTTPResponse:
+		return r.Timing
+	case *HTTPResponse:
+		return r.Timing
+	case HTTPTestResponse:
+		return r.Timing
+	case *HTTPTestResponse:
+		return r.Timing
+	case *cachedResponse:
+		return TimingOf(r.response)
+	}
+	return nil
+}
+
+// tracer records the phases of a request with httptrace
+type tracer struct {
+	mutex                                   sync.Mutex
+	start, dnsStart, connectStart, tlsStart time.Time
+	dns, connect, tls, firstByte            time.Duration
+}
+
+// withTrace returns a context which traces the request to the tracer
+func (t *tracer) withTrace(ctx context.Context) context.Context {
+	since := func(start time.Time) time.Duration {
+		if start.IsZero() {
+			return 0
+		}
+		return time.Since(start)
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.dns = since(t.dnsStart)
+		},
+		ConnectStart: func(network, addr string) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.connect = since(t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.tls = since(t.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.firstByte = since(t.start)
+		},
+	})
+}
+
+// timing returns the recorded timing
+func (t *tracer) timing() *Timing {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return &Timing{
+		Total:     time.Since(t.start),
+		DNS:       t.dns,
+		Connect:   t.connect,
+		TLS:       t.tls,
+		FirstByte: t.firstByte,
+	}
+}
+
+// RespondsWithin tests if the response is received within
+// the given duration. The response must have timing metadata
+// (see TimingOf).
+func RespondsWithin(d time.Duration) Expectation {
+	return Describe(
+		fmt.Sprintf("responds within %s", d),
+		func(ctx context.Context, resp Response) (err error) {
+			timing := TimingOf(resp)
+			if timing == nil {
+				ctxErr := NewContextError("response has no timing information")
+				ctxErr.Prepend("ref", "response.timing")
+				return ctxErr
+			}
+			if timing.Total > d {
+				ctxErr := NewContextError("expected response within %s, took %s", d, timing.Total)
+				ctxErr.Prepend("ref", "response.timing")
+				timing.appendTo(ctxErr)
+				return ctxErr
+			}
+			return
+		})
+}
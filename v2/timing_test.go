@@ -0,0 +1,84 @@
+package restit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestRespondsWithin(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	resp, err := service.Retrieve("/post/1").
+		Expect(restit.RespondsWithin(time.Second)).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if timing := restit.TimingOf(resp); timing == nil {
+		t.Fatalf("expected timing, got nil")
+	} else if timing.Total < 50*time.Millisecond {
+		t.Errorf("unexpected total duration: %s", timing.Total)
+	}
+
+	_, err = service.Retrieve("/post/1").
+		Expect(restit.RespondsWithin(10 * time.Millisecond)).
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	ctxErr := err.(restit.ContextError)
+	if want, have := "response.timing", ctxErr.Get("ref"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := "expected response within 10ms, took ", ctxErr.Error(); !strings.HasPrefix(have, want) {
+		t.Errorf("expected prefix %#v, got %#v", want, have)
+	}
+	if ctxErr.Get("total") == nil {
+		t.Errorf("expected total duration in context, got: %s", ctxErr.Log())
+	}
+}
+
+func TestRespondsWithin_HTTPHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	// new transport to trace a new connection
+	service := restit.NewHTTPService(server.URL, restit.WithTransport(&http.Transport{}))
+	resp, err := service.Retrieve("/post/1").
+		Expect(restit.RespondsWithin(time.Second)).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	timing := restit.TimingOf(resp)
+	if timing == nil {
+		t.Fatalf("expected timing, got nil")
+	}
+	if timing.Connect <= 0 {
+		t.Errorf("expected connect duration, got %s", timing.Connect)
+	}
+	if timing.FirstByte < 20*time.Millisecond || timing.FirstByte > timing.Total {
+		t.Errorf("unexpected first byte duration: %s (total %s)", timing.FirstByte, timing.Total)
+	}
+
+	_, err = service.Retrieve("/post/1").
+		Expect(restit.RespondsWithin(time.Millisecond)).
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if ctxErr := err.(restit.ContextError); ctxErr.Get("first_byte") == nil {
+		t.Errorf("expected first byte duration in context, got: %s", ctxErr.Log())
+	}
+}
@@ -0,0 +1,229 @@
+package restit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-restit/lzjson"
+)
+
+// reVar matches variable references (e.g. "{{postID}}"), literal
+// or percent-encoded
+var reVar = regexp.MustCompile(`(?i)(?:\{\{|%7B%7B)(\w+)(?:\}\}|%7D%7D)`)
+
+// Vars is a variable store shared by the cases of a Service
+// or a Scenario. Variables are captured from responses with
+// Case.Capture and referenced as "{{name}}" in the paths,
+// queries, headers and body of later cases.
+type Vars struct {
+	mutex  sync.RWMutex
+	values map[string]string
+	setBy  map[string]string
+}
+
+// NewVars creates an empty variable store
+func NewVars() *Vars {
+	return &Vars{
+		values: make(map[string]string),
+		setBy:  make(map[string]string),
+	}
+}
+
+// Set sets the value of a variable
+func (v *Vars) Set(name, value string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.values[name] = value
+}
+
+// Get returns the value of a variable, and if it is set
+func (v *Vars) Get(name string) (value string, ok bool) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	value, ok = v.values[name]
+	return
+}
+
+// declare records the case which is supposed to set the variable
+func (v *Vars) declare(name, caseDesc string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.setBy[name] = caseDesc
+}
+
+// missingError returns the ContextError of an unset variable
+func (v *Vars) missingError(name, caseDesc string) ContextError {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	ctxErr := NewContextError("variable %#v is not set", name)
+	ctxErr.Prepend("ref", "vars."+name)
+	ctxErr.Append("case", caseDesc)
+	if setBy, ok := v.setBy[name]; ok {
+		ctxErr.Append("set_by", setBy)
+	} else {
+		ctxErr.Append("set_by", "(no case captures it)")
+	}
+	return ctxErr
+}
+
+// interpolate replaces the variable references in str with
+// the escaped values
+func (v *Vars) interpolate(str string, escape func(string) string) (result string, missing string) {
+	result = reVar.ReplaceAllStringFunc(str, func(ref string) string {
+		name := reVar.FindStringSubmatch(ref)[1]
+		value, ok := v.Get(name)
+		if !ok {
+			if missing == "" {
+				missing = name
+			}
+			return ref
+		}
+		return escape(value)
+	})
+	return
+}
+
+// interpolateRequest replaces the variable references in the path,
+// query, headers and JSON body of the request. References to unset
+// variables in the body are left as-is.
+func (v *Vars) interpolateRequest(req *http.Request, caseDesc string) (err error) {
+	noEscape := func(s string) string { return s }
+
+	escapedPath, missing := v.interpolate(req.URL.EscapedPath(), url.PathEscape)
+	if missing != "" {
+		return v.missingError(missing, caseDesc)
+	}
+	if err = setPath(req.URL, escapedPath); err != nil {
+		return
+	}
+	if req.URL.RawQuery, missing = v.interpolate(req.URL.RawQuery, url.QueryEscape); missing != "" {
+		return v.missingError(missing, caseDesc)
+	}
+	for key, vals := range req.Header {
+		for i := range vals {
+			if vals[i], missing = v.interpolate(vals[i], noEscape); missing != "" {
+				return v.missingError(missing, caseDesc)
+			}
+		}
+		req.Header[key] = vals
+	}
+
+	// other bodies (e.g. multipart or binary) are sent as-is
+	if req.Body == nil || req.Body == http.NoBody ||
+		!strings.Contains(req.Header.Get("Content-Type"), "json") {
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return
+	}
+	req.Body.Close()
+	result, _ := v.interpolate(string(body), func(s string) string {
+		b, _ := json.Marshal(s)
+		return string(b[1 : len(b)-1])
+	})
+	req.Body = ioutil.NopCloser(strings.NewReader(result))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(result)), nil
+	}
+	req.ContentLength = int64(len(result))
+	return
+}
+
+// capture defines how to capture a variable from response
+type capture struct {
+	name   string
+	path   string
+	header string
+}
+
+// Do captures the variable from the response
+func (c capture) Do(vars *Vars, resp Response) (err error) {
+	if c.header != "" {
+		value := resp.Header().Get(c.header)
+		if value == "" {
+			ctxErr := NewContextError("unable to capture %#v from header %#v", c.name, c.header)
+			ctxErr.Prepend("ref", "header."+c.header)
+			return ctxErr
+		}
+		vars.Set(c.name, value)
+		return
+	}
+
+	root, err := resp.JSON()
+	if err != nil {
+		ctxErr := NewContextError("unable to capture %#v, response is not JSON (%s)", c.name, err)
+		ctxErr.Prepend("ref", "response")
+		return ctxErr
+	}
+	node, pathErr := lookupPath(root, c.path)
+	if pathErr != nil {
+		ctxErr := NewContextError("unable to capture %#v from %#v", c.name, c.path)
+		ctxErr.Prepend("ref", pathErr.Get("ref"))
+		return ctxErr
+	}
+	switch node.Type() {
+	case lzjson.TypeString:
+		vars.Set(c.name, node.String())
+	default:
+		vars.Set(c.name, string(bytes.TrimSpace(node.Raw())))
+	}
+	return
+}
+
+// desc returns a short description of the case for error messages
+func (c *Case) desc() string {
+	if c.Request == nil {
+		return ""
+	}
+	if c.pathTmpl != "" {
+		return c.Request.Method + " " + c.pathTmpl
+	}
+	return c.Request.Method + " " + c.Request.URL.Path
+}
+
+// WithVars sets the variable store of the case, replacing
+// the one from Service. Variables captured by the case are
+// declared in the new store.
+func (c *Case) WithVars(vars *Vars) *Case {
+	c.vars = vars
+	if vars != nil {
+		for _, capture := range c.captures {
+			vars.declare(capture.name, c.desc())
+		}
+	}
+	return c
+}
+
+// Capture stores the value of the JSON path (e.g. "post.id" or
+// "posts[0].id", see LengthIs for the syntax) in the JSON response
+// as variable after all expectations pass. The first match is used.
+// Later cases may reference it as "{{name}}".
+func (c *Case) Capture(name, path string) *Case {
+	if c.vars == nil {
+		return c.fail(fmt.Errorf("unable to capture %#v: case has no variable store", name))
+	}
+	c.vars.declare(name, c.desc())
+	c.captures = append(c.captures, capture{name: name, path: path})
+	return c
+}
+
+// CaptureHeader stores the value of the response header as variable
+// after all expectations pass. Later cases may reference it as
+// "{{name}}".
+func (c *Case) CaptureHeader(name, header string) *Case {
+	if c.vars == nil {
+		return c.fail(fmt.Errorf("unable to capture %#v: case has no variable store", name))
+	}
+	c.vars.declare(name, c.desc())
+	c.captures = append(c.captures, capture{name: name, header: header})
+	return c
+}
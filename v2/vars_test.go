@@ -0,0 +1,139 @@
+package restit_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestCase_Capture(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Header().Set("X-Request-ID", "req 1")
+			w.Write([]byte(`{"post":{"id":"post/1","seq":42},"tags":["foo","bar"]}`))
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]string{
+			"uri":    r.URL.RequestURI(),
+			"header": r.Header.Get("X-Request-ID"),
+			"body":   string(b),
+		})
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	if _, err := service.Create(nil, "/posts").
+		Capture("postID", "post.id").
+		Capture("seq", "post.seq").
+		Capture("tag", "tags.1").
+		CaptureHeader("requestID", "X-Request-ID").
+		Do(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	resp, err := service.Update(map[string]string{"id": "{{postID}}", "tag": "{{tag}}"}, "/post/{{postID}}?seq={{seq}}").
+		AddHeader("X-Request-ID", "{{requestID}}").
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var result map[string]string
+	if err := json.NewDecoder(resp.Body()).Decode(&result); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "/api/post/post%2F1?seq=42", result["uri"]; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := "req 1", result["header"]; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := `{"id":"post/1","tag":"bar"}`, result["body"]; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestCase_Capture_Missing(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"post":{}}`))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	// capture fails
+	_, err := service.Create(nil, "/posts").
+		Capture("postID", "post.id").
+		Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `capture="postID" ref="response.post" message="unable to capture \"postID\" from \"post.id\""`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+
+	// variable never set
+	_, err = service.Retrieve("/post/{{postID}}").Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `ref="vars.postID" message="variable \"postID\" is not set" case="GET /api/post/{{postID}}" set_by="POST /api/posts"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
+
+func TestCase_Vars_Rerun(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(r.URL.RequestURI() + " " + r.Header.Get("X-ID") + " " + string(b)))
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+
+	testCase := service.Update(map[string]string{"id": "{{id}}"}, "/post/{{id}}?id={{id}}").
+		AddHeader("X-ID", "{{id}}")
+	for _, id := range []string{"1", "2"} {
+		service.Vars.Set("id", id)
+		resp, err := testCase.Do()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if want, have := `/api/post/`+id+`?id=`+id+` `+id+` {"id":"`+id+`"}`, resp.String(); want != have {
+			t.Errorf("expected %#v, got %#v", want, have)
+		}
+	}
+
+	// the case itself is not interpolated
+	if want, have := "/api/post/%7B%7Bid%7D%7D", testCase.Clone().Request.URL.EscapedPath(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestCase_Vars_Body(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	})
+	service := restit.NewHTTPTestService("/api", handler)
+	service.Vars.Set("id", "post-1")
+
+	// unset variables in JSON body are left as-is
+	resp, err := service.Create(map[string]string{"id": "{{id}}", "template": "Hello {{name}}"}, "/tmpl").Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := `{"id":"post-1","template":"Hello {{name}}"}`, resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// other bodies are not interpolated
+	resp, err = service.Create(nil, "/files").
+		AttachFile("f", "a.bin", strings.NewReader("x{{id}}y{{abc}}")).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "x{{id}}y{{abc}}", resp.String(); !strings.Contains(have, want) {
+		t.Errorf("expected %#v in %#v", want, have)
+	}
+}
@@ -0,0 +1,330 @@
+package restit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// RecorderMode is the mode of Recorder
+type RecorderMode int
+
+// Recorder modes
+const (
+	// ModeReplay serves the responses from the cassette
+	ModeReplay RecorderMode = iota
+
+	// ModeRecord handles the requests with the inner handler and
+	// overwrites the cassette with the interactions on Flush or Close
+	ModeRecord
+
+	// ModeAuto replays if the cassette file exists, or records
+	// otherwise
+	ModeAuto
+)
+
+// BodyEncodingBase64 is the body encoding of recorded bodies which
+// are not valid UTF-8 (e.g. images)
+const BodyEncodingBase64 = "base64"
+
+// Cassette is the file format of Recorder
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request / response pair
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request of an Interaction
+type RecordedRequest struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// RecordedResponse is the response of an Interaction
+type RecordedResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// encodeBody returns the body as-is if it is valid UTF-8, or
+// base64 encoded with BodyEncodingBase64
+func encodeBody(body []byte) (encoded, encoding string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), BodyEncodingBase64
+}
+
+// decodeBody decodes the recorded body of the encoding
+func decodeBody(encoded, encoding string) ([]byte, error) {
+	if encoding == BodyEncodingBase64 {
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+	return []byte(encoded), nil
+}
+
+// Recorder implements CaseHandler. In record mode, it passes the
+// requests to the inner Handler and writes the interactions to
+// a JSON cassette file. In replay mode, it serves the responses
+// from the cassette without the inner Handler.
+//
+// Requests are matched by method, URL, body and the headers in
+// MatchHeaders. Each interaction is replayed once in recorded
+// order; when all matching interactions are used, the last one
+// is replayed again.
+//
+// In record mode, the cassette is written by Flush or Close.
+type Recorder struct {
+	Path    string
+	Mode    RecorderMode
+	Handler CaseHandler
+
+	// MatchHeaders are the request headers to match on replay
+	MatchHeaders []string
+
+	// RedactHeaders are the request and response headers which
+	// values are replaced by Redacted in the cassette
+	RedactHeaders []string
+
+	// Redact modifies the interaction before it is stored, or
+	// matched on replay (e.g. to remove secrets in the body)
+	Redact func(*Interaction)
+
+	mutex    sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewRecorder creates a Recorder of the cassette file path
+// which redacts the SensitiveHeaders
+func NewRecorder(path string, mode RecorderMode, handler CaseHandler) *Recorder {
+	return &Recorder{
+		Path:          path,
+		Mode:          mode,
+		Handler:       handler,
+		RedactHeaders: append([]string(nil), SensitiveHeaders...),
+	}
+}
+
+// Handle implements CaseHandler
+func (r *Recorder) Handle(req *http.Request) (resp Response, err error) {
+	r.mutex.Lock()
+	err = r.load()
+	mode := r.Mode
+	r.mutex.Unlock()
+
+	if err != nil {
+		return
+	}
+	if mode == ModeRecord {
+		return r.record(req)
+	}
+	return r.replay(req)
+}
+
+// Flush writes the recorded interactions to the cassette file.
+// It does nothing if the recorder is not recording.
+func (r *Recorder) Flush() (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.Mode != ModeRecord || r.cassette == nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(r.Path, data, 0644)
+	}
+	if err != nil {
+		ctxErr := NewContextError("unable to save cassette (%s)", err)
+		ctxErr.Prepend("ref", "cassette")
+		ctxErr.Append("cassette", r.Path)
+		return ctxErr
+	}
+	return
+}
+
+// Close flushes the recorded interactions to the cassette file
+func (r *Recorder) Close() error {
+	return r.Flush()
+}
+
+// load initializes the cassette for the mode
+func (r *Recorder) load() (err error) {
+	if r.cassette != nil {
+		return
+	}
+	if r.Mode == ModeAuto {
+		r.Mode = ModeReplay
+		if _, statErr := os.Stat(r.Path); os.IsNotExist(statErr) {
+			r.Mode = ModeRecord
+		}
+	}
+	r.cassette = &Cassette{}
+	if r.Mode == ModeRecord {
+		return
+	}
+
+	data, err := ioutil.ReadFile(r.Path)
+	if err == nil {
+		err = json.Unmarshal(data, r.cassette)
+	}
+	if err != nil {
+		r.cassette = nil
+		ctxErr := NewContextError("unable to load cassette (%s)", err)
+		ctxErr.Prepend("ref", "cassette")
+		ctxErr.Append("cassette", r.Path)
+		return ctxErr
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return
+}
+
+// recordRequest reads the request into RecordedRequest and
+// restores the body
+func recordRequest(req *http.Request) (recorded RecordedRequest, err error) {
+	recorded = RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		var body []byte
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		recorded.Body, recorded.BodyEncoding = encodeBody(body)
+	}
+	return
+}
+
+// redact redacts the interaction for storing or matching
+func (r *Recorder) redact(interaction *Interaction) {
+	for _, key := range r.RedactHeaders {
+		for _, header := range []http.Header{interaction.Request.Header, interaction.Response.Header} {
+			if vals, ok := header[http.CanonicalHeaderKey(key)]; ok {
+				for i := range vals {
+					vals[i] = Redacted
+				}
+			}
+		}
+	}
+	if r.Redact != nil {
+		r.Redact(interaction)
+	}
+}
+
+// record handles the request with the inner handler and appends
+// the interaction to the cassette
+func (r *Recorder) record(req *http.Request) (resp Response, err error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return
+	}
+	if r.Handler == nil {
+		ctxErr := NewContextError("recorder has no handler to record with")
+		ctxErr.Prepend("ref", "cassette")
+		ctxErr.Append("cassette", r.Path)
+		return nil, ctxErr
+	}
+	if resp, err = r.Handler.Handle(req); err != nil {
+		return
+	}
+	resp = CacheResponse(resp)
+	interaction := Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode(),
+			Header:     resp.Header().Clone(),
+		},
+	}
+	interaction.Response.Body, interaction.Response.BodyEncoding = encodeBody([]byte(resp.String()))
+	r.redact(&interaction)
+
+	r.mutex.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mutex.Unlock()
+	return
+}
+
+// matches tests if the recorded request matches the incoming one
+func (r *Recorder) matches(recorded, incoming RecordedRequest) bool {
+	if recorded.Method != incoming.Method ||
+		recorded.URL != incoming.URL ||
+		recorded.Body != incoming.Body ||
+		recorded.BodyEncoding != incoming.BodyEncoding {
+		return false
+	}
+	for _, key := range r.MatchHeaders {
+		if recorded.Header.Get(key) != incoming.Header.Get(key) {
+			return false
+		}
+	}
+	return true
+}
+
+// replay serves the response of the matching interaction
+func (r *Recorder) replay(req *http.Request) (resp Response, err error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return
+	}
+	incoming := Interaction{Request: recorded}
+	r.redact(&incoming)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	found := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !r.matches(interaction.Request, incoming.Request) {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found < 0 {
+		ctxErr := NewContextError("no interaction in cassette matches the request")
+		ctxErr.Prepend("ref", "cassette")
+		ctxErr.Append("cassette", r.Path)
+		ctxErr.Append("method", req.Method)
+		ctxErr.Append("url", req.URL.String())
+		return nil, ctxErr
+	}
+	r.used[found] = true
+
+	recordedResp := r.cassette.Interactions[found].Response
+	body, err := decodeBody(recordedResp.Body, recordedResp.BodyEncoding)
+	if err != nil {
+		ctxErr := NewContextError("unable to decode recorded body (%s)", err)
+		ctxErr.Prepend("ref", "cassette")
+		ctxErr.Append("cassette", r.Path)
+		return nil, ctxErr
+	}
+	w := httptest.NewRecorder()
+	for key, vals := range recordedResp.Header {
+		w.Header()[key] = append([]string(nil), vals...)
+	}
+	w.WriteHeader(recordedResp.StatusCode)
+	w.Write(body)
+	resp = HTTPTestResponse{RawResponse: w}
+	return
+}
@@ -0,0 +1,257 @@
+package restit_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	restit "github.com/go-restit/restit/v2"
+)
+
+func TestRecorder(t *testing.T) {
+	count := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("Set-Cookie", "session=secret")
+		body, _ := ioutil.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"count": count,
+			"body":  string(body),
+		})
+	})
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// record
+	recorder := restit.NewRecorder(path, restit.ModeRecord,
+		restit.CaseHandlerFunc(restit.HTTPTestHandler(handler)))
+	recorder.RedactHeaders = []string{"Authorization", "Set-Cookie"}
+	service := restit.NewHTTPTestService("/api", handler)
+	service.Handler = recorder
+	service.Auth = restit.BearerAuth("some_token")
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.Retrieve("/post/1").Do(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if _, err := service.Create(map[string]string{"foo": "bar"}, "/posts").Do(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "some_token") {
+		t.Errorf("secrets are not redacted:\n%s", data)
+	}
+
+	// replay without the inner handler
+	recorder = restit.NewRecorder(path, restit.ModeReplay, nil)
+	recorder.RedactHeaders = []string{"Authorization", "Set-Cookie"}
+	service.Handler = recorder
+
+	for i, want := range []string{`"count":1`, `"count":2`, `"count":2`} {
+		resp, err := service.Retrieve("/post/1").Do()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if have := resp.String(); !strings.Contains(have, want) {
+			t.Errorf("replay #%d: expected %s, got %s", i, want, have)
+		}
+	}
+	resp, err := service.Create(map[string]string{"foo": "bar"}, "/posts").Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := `"count":3`, resp.String(); !strings.Contains(have, want) {
+		t.Errorf("expected %s, got %s", want, have)
+	}
+	if want, have := restit.Redacted, resp.Header().Get("Set-Cookie"); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if want, have := 3, count; want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+
+	// unmatched request
+	_, err = service.Create(map[string]string{"foo": "baz"}, "/posts").Do()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	} else if want, have := `ref="cassette" message="no interaction in cassette matches the request" cassette="`+path+`" method="POST" url="/api/posts" curl="curl -X POST http://localhost/api/posts -H 'Authorization: [REDACTED]' -H 'Content-Type: application/json' --data-raw '{\"foo\":\"baz\"}'"`,
+		err.(restit.ContextError).Log(); want != have {
+		t.Errorf("\nexpected: %s\ngot:      %s", want, have)
+	}
+}
+
+func TestRecorder_Auto(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Version")))
+	})
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	service := restit.NewHTTPTestService("/api", handler)
+
+	// record when the cassette does not exist
+	recorder := restit.NewRecorder(path, restit.ModeAuto,
+		restit.CaseHandlerFunc(restit.HTTPTestHandler(handler)))
+	service.Handler = recorder
+	if _, err := service.Retrieve("/post/1").AddHeader("X-Version", "1").Do(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// replay when it exists, matching on selected header
+	recorder = restit.NewRecorder(path, restit.ModeAuto, nil)
+	recorder.MatchHeaders = []string{"X-Version"}
+	service.Handler = recorder
+	resp, err := service.Retrieve("/post/1").AddHeader("X-Version", "1").Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "1", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+	if _, err = service.Retrieve("/post/1").AddHeader("X-Version", "2").Do(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestRecorder_Binary(t *testing.T) {
+	binary := []byte{0x89, 'P', 'N', 'G', 0xff, 0x00}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(append(body, binary...))
+	})
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	service := restit.NewHTTPTestService("/api", handler)
+
+	recorder := restit.NewRecorder(path, restit.ModeRecord,
+		restit.CaseHandlerFunc(restit.HTTPTestHandler(handler)))
+	service.Handler = recorder
+	if _, err := service.Create(binary, "/images").
+		WithEncoder(restit.RawEncoder("image/png")).
+		Do(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := `"body_encoding": "base64"`, string(data); !strings.Contains(have, want) {
+		t.Errorf("expected %s in cassette, got %s", want, have)
+	}
+
+	service.Handler = restit.NewRecorder(path, restit.ModeReplay, nil)
+	resp, err := service.Create(binary, "/images").
+		WithEncoder(restit.RawEncoder("image/png")).
+		Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := string(binary)+string(binary), resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestRecorder_Multipart(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1024); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(r.FormValue("title")))
+	})
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	service := restit.NewHTTPTestService("/api", handler)
+	upload := func() *restit.Case {
+		return service.Create(nil, "/images").
+			AddFormField("title", "hello").
+			AttachFile("image", "image.png", strings.NewReader("dummy image"))
+	}
+
+	recorder := restit.NewRecorder(path, restit.ModeRecord,
+		restit.CaseHandlerFunc(restit.HTTPTestHandler(handler)))
+	service.Handler = recorder
+	if _, err := upload().Do(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	service.Handler = restit.NewRecorder(path, restit.ModeReplay, nil)
+	resp, err := upload().Do()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := "hello", resp.String(); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}
+
+func TestRecorder_Concurrent(t *testing.T) {
+	// both requests must be in the handler at the same time
+	arrived := make(chan struct{}, 2)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		deadline := time.After(time.Second)
+		for len(arrived) < 2 {
+			select {
+			case <-deadline:
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	})
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder := restit.NewRecorder(path, restit.ModeRecord,
+		restit.CaseHandlerFunc(restit.HTTPTestHandler(handler)))
+	service := restit.NewHTTPTestService("/api", handler)
+	service.Handler = recorder
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.Retrieve("/post/1").
+				Expect(restit.StatusCodeIs(http.StatusOK)).
+				Do(); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// nothing is written before Close
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected cassette not to exist, got %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cassette := restit.Cassette{}
+	data, _ := ioutil.ReadFile(path)
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, have := 2, len(cassette.Interactions); want != have {
+		t.Errorf("expected %#v, got %#v", want, have)
+	}
+}